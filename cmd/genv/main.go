@@ -0,0 +1,448 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/lonegunmanb/genv/pkg"
+	"github.com/spf13/cobra"
+)
+
+// genv is a single control plane for every tool defined in the registry directory. Installed
+// under another name, like a symlink named vault created by "genv link", it acts as that
+// binary's shim.
+func main() {
+	ctx, cancel := context.WithCancel(context.Background())
+	// Listen for interrupt signal (Ctrl + C) and cancel the context when received
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	go func() {
+		for range c {
+			cancel()
+		}
+	}()
+
+	homeDir, err := defaultHomeDir()
+	if err != nil {
+		panic(err.Error())
+	}
+	registryDir := os.Getenv("GENV_REGISTRY_DIR")
+	// The registry lives in the home dir unless it's set explicitly.
+	registry := func() string {
+		if registryDir != "" {
+			return registryDir
+		}
+		return filepath.Join(homeDir, pkg.RegistryDirName)
+	}
+
+	invokedAs := strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe")
+	if invokedAs != "genv" {
+		os.Exit(runShim(ctx, invokedAs, homeDir, registry()))
+	}
+
+	loadRegistry := func() (*pkg.Registry, error) {
+		return pkg.LoadRegistry(registry())
+	}
+	loadEnv := func(tool string) (*pkg.Env, error) {
+		registry, err := loadRegistry()
+		if err != nil {
+			return nil, err
+		}
+		return registry.Env(tool, homeDir, ctx)
+	}
+	// completeArgs completes the tool name first, then the installed versions of that tool.
+	completeArgs := func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		var candidates []string
+		switch len(args) {
+		case 0:
+			if registry, err := loadRegistry(); err == nil {
+				candidates = registry.Tools()
+			}
+		case 1:
+			if env, err := loadEnv(args[0]); err == nil {
+				candidates, _ = env.ListInstalled()
+			}
+		}
+		var completions []string
+		for _, candidate := range candidates {
+			if strings.HasPrefix(candidate, toComplete) {
+				completions = append(completions, candidate)
+			}
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+	}
+
+	var rootCmd = &cobra.Command{
+		Use:           "genv",
+		Short:         "genv manages the versions of every tool defined in its registry",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	rootCmd.PersistentFlags().StringVar(&homeDir, "home-dir", homeDir, "Directory that holds installed versions, defaults to $GENV_HOME_DIR or ~/.genv")
+	rootCmd.PersistentFlags().StringVar(&registryDir, "registry", registryDir, "Directory of tool definitions, defaults to $GENV_REGISTRY_DIR or <home-dir>/registry")
+
+	var cmdTools = &cobra.Command{
+		Use:   "tools",
+		Short: "List all registered tools",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			registry, err := loadRegistry()
+			if err != nil {
+				return err
+			}
+			for _, name := range registry.Tools() {
+				tool, err := registry.Tool(name)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("%s\t%s\n", name, strings.Join(tool.Binaries, ", "))
+			}
+			return nil
+		},
+	}
+
+	var cmdInstall = &cobra.Command{
+		Use:               "install [tool] [version]",
+		Short:             "Install a specific version of a tool",
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: completeArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			env, err := loadEnv(args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Installing %s version: %s\n", args[0], args[1])
+			return env.Install(args[1])
+		},
+	}
+
+	var cmdUse = &cobra.Command{
+		Use:               "use [tool] [version]",
+		Short:             "Use a specific version of a tool",
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: completeArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			env, err := loadEnv(args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Using %s version: %s\n", args[0], args[1])
+			return env.Use(args[1])
+		},
+	}
+
+	var uninstallForce bool
+	var cmdUninstall = &cobra.Command{
+		Use:               "uninstall [tool] version|range...",
+		Short:             "Uninstall versions of a tool, given one by one or as semver ranges like \"<1.5\"",
+		Args:              cobra.MinimumNArgs(2),
+		ValidArgsFunction: completeArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			env, err := loadEnv(args[0])
+			if err != nil {
+				return err
+			}
+			pwd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+			return env.UninstallMatching(args[1:], pkg.UninstallOptions{
+				Dir:   pwd,
+				Force: uninstallForce,
+				In:    os.Stdin,
+				Out:   os.Stdout,
+			})
+		},
+	}
+	cmdUninstall.Flags().BoolVarP(&uninstallForce, "force", "f", false, "Uninstall pinned and active versions without asking")
+
+	var cmdList = &cobra.Command{
+		Use:               "list [tool]",
+		Short:             "List all installed versions of a tool, the active one is marked with *",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			env, err := loadEnv(args[0])
+			if err != nil {
+				return err
+			}
+			installed, err := env.ListInstalled()
+			if err != nil {
+				return err
+			}
+			current, err := env.CurrentVersion()
+			if err != nil {
+				return err
+			}
+			for _, i := range installed {
+				if current != nil && *current == i {
+					fmt.Printf("* %s\n", i)
+					continue
+				}
+				fmt.Printf("  %s\n", i)
+			}
+			return nil
+		},
+	}
+
+	var pathBinary string
+	var cmdBinaryPath = &cobra.Command{
+		Use:               "path [tool]",
+		Short:             "Get the full path to the current binary of a tool",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			env, err := loadEnv(args[0])
+			if err != nil {
+				return err
+			}
+			path, err := env.CurrentBinaryPath(pathBinary)
+			if err != nil {
+				return err
+			}
+			if path == nil {
+				return fmt.Errorf("no version selected, please run use first")
+			}
+			fmt.Print(*path)
+			return nil
+		},
+	}
+	cmdBinaryPath.Flags().StringVar(&pathBinary, "binary", "", "Binary to get the path of, defaults to the tool's primary binary")
+
+	var cmdExec = &cobra.Command{
+		Use:               "exec [tool] [version] -- [args...]",
+		Short:             "Run a specific version of a tool once without switching to it",
+		Args:              cobra.MinimumNArgs(2),
+		ValidArgsFunction: completeArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			env, err := loadEnv(args[0])
+			if err != nil {
+				return err
+			}
+			binaryArgs := args[2:]
+			if len(binaryArgs) > 0 && binaryArgs[0] == "--" {
+				binaryArgs = binaryArgs[1:]
+			}
+			err = env.Exec(args[1], binaryArgs)
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				os.Exit(exitErr.ExitCode())
+			}
+			return err
+		},
+	}
+	// Everything after the version belongs to the binary, even when it looks like a flag.
+	cmdExec.Flags().SetInterspersed(false)
+
+	var linkBinDir string
+	var linkForce bool
+	var cmdLink = &cobra.Command{
+		Use:   "link [tool...]",
+		Short: "Create shims for the binaries of the given tools, or of all tools, next to genv",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			registry, err := loadRegistry()
+			if err != nil {
+				return err
+			}
+			executable, err := os.Executable()
+			if err != nil {
+				return err
+			}
+			if executable, err = filepath.EvalSymlinks(executable); err != nil {
+				return err
+			}
+			if linkBinDir == "" {
+				linkBinDir = filepath.Dir(executable)
+			}
+			tools := args
+			if len(tools) == 0 {
+				tools = registry.Tools()
+			}
+			for _, name := range tools {
+				tool, err := registry.Tool(name)
+				if err != nil {
+					return err
+				}
+				for _, binary := range tool.Binaries {
+					shim := filepath.Join(linkBinDir, binary)
+					if runtime.GOOS == "windows" {
+						shim += ".exe"
+					}
+					if err = linkShim(executable, shim, linkForce); err != nil {
+						return err
+					}
+					fmt.Printf("Linked %s\n", shim)
+				}
+			}
+			return nil
+		},
+	}
+	cmdLink.Flags().StringVar(&linkBinDir, "bin-dir", "", "Directory to create the shims in, defaults to the directory of genv")
+	cmdLink.Flags().BoolVarP(&linkForce, "force", "f", false, "Replace files at the shim paths that aren't genv shims")
+
+	var syncManifest string
+	var syncParallel int
+	var cmdSync = &cobra.Command{
+		Use:   "sync",
+		Short: "Install every tool version pinned by the project manifest, .genv.toml or .tool-versions",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if syncManifest == "" {
+				pwd, err := os.Getwd()
+				if err != nil {
+					return err
+				}
+				if syncManifest, err = pkg.FindProjectManifest(pwd); err != nil {
+					return err
+				}
+				if syncManifest == "" {
+					return fmt.Errorf("no %s or %s found in %s or its parents", pkg.ProjectManifestName, pkg.ToolVersionsName, pwd)
+				}
+			}
+			pinned, err := pkg.LoadProjectManifest(syncManifest)
+			if err != nil {
+				return err
+			}
+			registry, err := loadRegistry()
+			if err != nil {
+				return err
+			}
+			var jobs []pkg.SyncJob
+			var unknown []string
+			for _, p := range pinned {
+				env, err := registry.Env(p.Tool, homeDir, ctx)
+				if err != nil {
+					unknown = append(unknown, p.Tool)
+					continue
+				}
+				jobs = append(jobs, pkg.SyncJob{
+					Tool:    p.Tool,
+					Version: p.Version,
+					Env:     env,
+				})
+			}
+			if len(unknown) > 0 {
+				return fmt.Errorf("%s pins tools that aren't in the registry: %s", syncManifest, strings.Join(unknown, ", "))
+			}
+			fmt.Printf("Syncing %d tool(s) from %s\n", len(jobs), syncManifest)
+			return pkg.Sync(jobs, syncParallel, os.Stdout)
+		},
+	}
+	cmdSync.Flags().StringVar(&syncManifest, "manifest", "", "Project manifest to sync, defaults to the nearest .genv.toml or .tool-versions")
+	cmdSync.Flags().IntVarP(&syncParallel, "parallel", "p", 4, "Maximum number of concurrent installs")
+
+	rootCmd.AddCommand(cmdTools, cmdInstall, cmdUse, cmdUninstall, cmdList, cmdBinaryPath, cmdExec, cmdLink, cmdSync)
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println("Error executing command:", err)
+		os.Exit(1)
+	}
+}
+
+// runShim runs the current version of the tool that ships binary and returns its exit code.
+func runShim(ctx context.Context, binary, homeDir, registryDir string) int {
+	registry, err := pkg.LoadRegistry(registryDir)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "genv: %s\n", err)
+		return 1
+	}
+	tool, ok := registry.ToolForBinary(binary)
+	if !ok {
+		_, _ = fmt.Fprintf(os.Stderr, "genv: no tool in %s ships %s\n", registryDir, binary)
+		return 1
+	}
+	env, err := registry.Env(tool.Name, homeDir, ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "genv: %s\n", err)
+		return 1
+	}
+	pwd, err := os.Getwd()
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "genv: %s\n", err)
+		return 1
+	}
+	version, err := env.ResolveVersion(pwd)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "genv: %s\n", err)
+		return 1
+	}
+	if version == nil {
+		defaultVersion := os.Getenv(fmt.Sprintf("GENV_%s_DEFAULT_VERSION", strings.ToUpper(strings.ReplaceAll(tool.Name, "-", "_"))))
+		if defaultVersion == "" {
+			defaultVersion = "latest"
+		}
+		if err = env.Use(defaultVersion); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "genv: %s\n", err)
+			return 1
+		}
+		version = &defaultVersion
+	}
+	if err = env.Install(*version); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "genv: %s\n", err)
+		return 1
+	}
+	path, err := env.CurrentBinaryPath(binary)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "genv: %s\n", err)
+		return 1
+	}
+	env.RecordUsage(*version)
+	cmd := exec.Command(*path, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err = cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		_, _ = fmt.Fprintf(os.Stderr, "genv: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+// linkShim links shim to the genv executable. An existing shim is replaced, anything else at that
+// path, like a binary installed by other means, only with force.
+func linkShim(executable, shim string, force bool) error {
+	if _, err := os.Lstat(shim); err == nil {
+		if !force && !sameFile(executable, shim) {
+			return fmt.Errorf("%s already exists and isn't a genv shim, use --force to replace it", shim)
+		}
+		if err = os.Remove(shim); err != nil {
+			return err
+		}
+	}
+	// Symlinks need extra privileges on Windows, a hard link works just as well for a shim.
+	if err := os.Symlink(executable, shim); err != nil {
+		return os.Link(executable, shim)
+	}
+	return nil
+}
+
+// sameFile tells whether path is, or links to, the file at executable.
+func sameFile(executable, path string) bool {
+	executableInfo, err := os.Stat(executable)
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && os.SameFile(executableInfo, info)
+}
+
+func defaultHomeDir() (string, error) {
+	if homeDir := os.Getenv("GENV_HOME_DIR"); homeDir != "" {
+		return homeDir, nil
+	}
+	userHomeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(userHomeDir, ".genv"), nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkShim(t *testing.T) {
+	dir := t.TempDir()
+	executable := filepath.Join(dir, "genv")
+	require.NoError(t, os.WriteFile(executable, []byte("genv"), 0755))
+	shim := filepath.Join(dir, "terraform")
+
+	require.NoError(t, linkShim(executable, shim, false))
+	assert.True(t, sameFile(executable, shim))
+	require.NoError(t, linkShim(executable, shim, false), "an existing shim is replaced")
+	assert.True(t, sameFile(executable, shim))
+}
+
+func TestLinkShim_KeepOtherBinaries(t *testing.T) {
+	dir := t.TempDir()
+	executable := filepath.Join(dir, "genv")
+	require.NoError(t, os.WriteFile(executable, []byte("genv"), 0755))
+	binary := filepath.Join(dir, "terraform")
+	require.NoError(t, os.WriteFile(binary, []byte("real terraform"), 0755))
+
+	err := linkShim(executable, binary, false)
+	assert.ErrorContains(t, err, "use --force")
+	content, err := os.ReadFile(binary)
+	require.NoError(t, err)
+	assert.Equal(t, "real terraform", string(content))
+
+	require.NoError(t, linkShim(executable, binary, true))
+	assert.True(t, sameFile(executable, binary))
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// GeneratedMarker heads every file the generator writes, an output directory is only overwritten
+// when its main.go carries it, so hand written code is never clobbered.
+const GeneratedMarker = "// Code generated by genv. DO NOT EDIT."
+
+// envTemplate renders the control plane's main.go and shimTemplate every shim's main.go, any
+// other template is rendered next to the control plane's main.go with the .tmpl suffix dropped.
+const (
+	envTemplate  = "env.go.tmpl"
+	shimTemplate = "shim.go.tmpl"
+)
+
+//go:embed templates/*.tmpl
+var embeddedTemplates embed.FS
+
+// executeCommand is a variable so tests can run the generator without a Go toolchain or network.
+// env is appended to the current environment.
+var executeCommand = func(wd string, env []string, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = wd
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// generator renders a single Go module into out: the control plane in the module root, so it's
+// installed under the env name, and one shim package per binary in out/<binaryName>.
+type generator struct {
+	out  string
+	data templateData
+	// templateDir holds templates that override the embedded ones by name, or add files to
+	// the control plane.
+	templateDir string
+	noInstall   bool
+	dryRun      bool
+	// release cross compiles and packages the module for platforms instead of installing it.
+	release        bool
+	releaseVersion string
+	releaseDir     string
+	platforms      []string
+	stdout         io.Writer
+}
+
+func (g *generator) run() error {
+	files, err := g.files()
+	if err != nil {
+		return err
+	}
+	if g.dryRun {
+		printTree(g.stdout, g.out, files)
+		return nil
+	}
+	if err = checkOutDir(g.out); err != nil {
+		return err
+	}
+	if err = removeGenerated(g.out); err != nil {
+		return err
+	}
+	for _, rel := range sortedPaths(files) {
+		dst := filepath.Join(g.out, rel)
+		if err = os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		if err = os.WriteFile(dst, files[rel], 0644); err != nil {
+			return err
+		}
+	}
+	return g.build()
+}
+
+// files returns the content of every generated file keyed by its slash separated path relative
+// to the output directory.
+func (g *generator) files() (map[string][]byte, error) {
+	templates, err := g.templates()
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]byte)
+	for name, tplt := range templates {
+		if name == shimTemplate {
+			continue
+		}
+		dst := "main.go"
+		if name != envTemplate {
+			dst = strings.TrimSuffix(name, ".tmpl")
+			if dst == "main.go" || dst == "go.mod" || dst == "go.sum" {
+				return nil, fmt.Errorf("template %s clashes with the generated %s", name, dst)
+			}
+		}
+		if files[dst], err = render(tplt, dst, g.data); err != nil {
+			return nil, err
+		}
+	}
+	shimTplt := templates[shimTemplate]
+	for _, binaryName := range g.data.BinaryNames {
+		if binaryName == g.data.Name {
+			return nil, fmt.Errorf("binary name %s clashes with the env name", binaryName)
+		}
+		shimData := g.data
+		shimData.BinaryName = binaryName
+		if files[binaryName+"/main.go"], err = render(shimTplt, "main.go", shimData); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func (g *generator) build() error {
+	goMod := filepath.Join(g.out, "go.mod")
+	if _, err := os.Stat(goMod); errors.Is(err, os.ErrNotExist) {
+		if err = executeCommand(g.out, nil, "go", "mod", "init", g.data.Name); err != nil {
+			return fmt.Errorf("failed to run 'go mod init' in %s: %w", g.out, err)
+		}
+	}
+	if err := executeCommand(g.out, nil, "go", "mod", "tidy"); err != nil {
+		return fmt.Errorf("failed to run 'go mod tidy' in %s: %w", g.out, err)
+	}
+	if g.release {
+		return g.buildRelease()
+	}
+	if g.noInstall {
+		return nil
+	}
+	if err := executeCommand(g.out, nil, "go", "install", "./..."); err != nil {
+		return fmt.Errorf("failed to run 'go install' in %s: %w", g.out, err)
+	}
+	return nil
+}
+
+// templates parses the embedded templates, then the ones in templateDir, which win by name.
+func (g *generator) templates() (map[string]*template.Template, error) {
+	templates := make(map[string]*template.Template)
+	if err := parseTemplates(embeddedTemplates, "templates", templates); err != nil {
+		return nil, err
+	}
+	if g.templateDir != "" {
+		if err := parseTemplates(os.DirFS(g.templateDir), ".", templates); err != nil {
+			return nil, err
+		}
+	}
+	return templates, nil
+}
+
+func parseTemplates(fsys fs.FS, dir string, templates map[string]*template.Template) error {
+	paths, err := fs.Glob(fsys, path.Join(dir, "*.tmpl"))
+	if err != nil {
+		return err
+	}
+	for _, p := range paths {
+		content, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.Base(p)
+		tplt, err := template.New(name).Parse(string(content))
+		if err != nil {
+			return err
+		}
+		templates[name] = tplt
+	}
+	return nil
+}
+
+// render executes the template, Go files get the generated marker.
+func render(tplt *template.Template, dst string, data templateData) ([]byte, error) {
+	var body bytes.Buffer
+	if err := tplt.Execute(&body, data); err != nil {
+		return nil, fmt.Errorf("rendering %s: %w", tplt.Name(), err)
+	}
+	if !strings.HasSuffix(dst, ".go") {
+		return body.Bytes(), nil
+	}
+	var buf bytes.Buffer
+	buf.WriteString(GeneratedMarker + "\n\n")
+	buf.Write(bytes.TrimLeft(body.Bytes(), "\n"))
+	return buf.Bytes(), nil
+}
+
+// checkOutDir accepts a missing or empty directory, or one whose main.go was generated by us.
+func checkOutDir(out string) error {
+	entries, err := os.ReadDir(out)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && len(entries) == 0) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	generated, err := isGenerated(filepath.Join(out, "main.go"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if !generated {
+		return fmt.Errorf("refusing to overwrite %s, it's not empty and wasn't generated by genv", out)
+	}
+	return nil
+}
+
+// removeGenerated deletes the files a previous run generated, so shims of binaries that were
+// dropped don't linger, and the directories they leave empty. go.mod and go.sum are kept, as
+// are directories that were already empty and anything under .git.
+func removeGenerated(out string) error {
+	out = filepath.Clean(out)
+	var dirs []string
+	err := filepath.WalkDir(out, func(p string, d fs.DirEntry, err error) error {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(p, ".go") {
+			return nil
+		}
+		generated, err := isGenerated(p)
+		if err != nil || !generated {
+			return err
+		}
+		if err = os.Remove(p); err != nil {
+			return err
+		}
+		dirs = append(dirs, filepath.Dir(p))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// A parent is only removed when the generated directories it held were all it had.
+	for _, dir := range dirs {
+		for ; dir != out && dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+			if entries, err := os.ReadDir(dir); err != nil || len(entries) > 0 || os.Remove(dir) != nil {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func isGenerated(path string) (bool, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	head := make([]byte, len(GeneratedMarker))
+	if _, err = io.ReadFull(f, head); err != nil {
+		return false, nil
+	}
+	return string(head) == GeneratedMarker, nil
+}
+
+// printTree prints the files that would be generated, go.mod included, as an indented tree.
+func printTree(w io.Writer, out string, files map[string][]byte) {
+	paths := append(sortedPaths(files), "go.mod")
+	sort.Strings(paths)
+	_, _ = fmt.Fprintf(w, "%s/\n", filepath.Clean(out))
+	printed := make(map[string]bool)
+	for _, p := range paths {
+		parts := strings.Split(p, "/")
+		for i := range parts {
+			key := strings.Join(parts[:i+1], "/")
+			if printed[key] {
+				continue
+			}
+			printed[key] = true
+			name := parts[i]
+			if i < len(parts)-1 {
+				name += "/"
+			}
+			_, _ = fmt.Fprintf(w, "%s%s\n", strings.Repeat("  ", i+1), name)
+		}
+	}
+}
+
+func sortedPaths(files map[string][]byte) []string {
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
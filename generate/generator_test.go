@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prashantv/gostub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testGenerator(out string, binaryNames ...string) *generator {
+	return &generator{
+		out: out,
+		data: templateData{
+			DownloadUrlTemplate: "https://releases.hashicorp.com/vault/{{ .Version }}/vault_{{ .Version }}_{{ .Os }}_{{ .Arch }}.zip",
+			Name:                "vaultenv",
+			UpperName:           "VAULTENV",
+			BinaryName:          binaryNames[0],
+			BinaryNames:         binaryNames,
+			GoBuildRepoUrl:      "https://github.com/hashicorp/vault.git",
+		},
+		stdout: &bytes.Buffer{},
+	}
+}
+
+func stubCommands(t *testing.T) *[]string {
+	var commands []string
+	stub := gostub.Stub(&executeCommand, func(wd string, env []string, name string, args ...string) error {
+		commands = append(commands, strings.Join(append([]string{name}, args...), " "))
+		if len(args) > 1 && args[0] == "mod" && args[1] == "init" {
+			return os.WriteFile(filepath.Join(wd, "go.mod"), []byte("module "+args[2]+"\n"), 0644)
+		}
+		return nil
+	})
+	t.Cleanup(stub.Reset)
+	return &commands
+}
+
+func TestGenerator_Run(t *testing.T) {
+	commands := stubCommands(t)
+	out := filepath.Join(t.TempDir(), "vaultenv")
+	err := testGenerator(out, "vault", "vault-helper").run()
+	require.NoError(t, err)
+	for _, f := range []string{"main.go", "vault/main.go", "vault-helper/main.go"} {
+		content, err := os.ReadFile(filepath.Join(out, f))
+		require.NoError(t, err, f)
+		assert.True(t, strings.HasPrefix(string(content), GeneratedMarker+"\n\npackage main"), f)
+	}
+	assert.Equal(t, []string{"go mod init vaultenv", "go mod tidy", "go install ./..."}, *commands)
+}
+
+func TestGenerator_NoInstall(t *testing.T) {
+	commands := stubCommands(t)
+	g := testGenerator(filepath.Join(t.TempDir(), "vaultenv"), "vault")
+	g.noInstall = true
+	require.NoError(t, g.run())
+	assert.Equal(t, []string{"go mod init vaultenv", "go mod tidy"}, *commands)
+}
+
+func TestGenerator_DryRunPrintsTreeWithoutWriting(t *testing.T) {
+	commands := stubCommands(t)
+	out := filepath.Join(t.TempDir(), "vaultenv")
+	g := testGenerator(out, "vault", "vault-helper")
+	g.dryRun = true
+	require.NoError(t, g.run())
+	expected := out + "/\n" +
+		"  go.mod\n" +
+		"  main.go\n" +
+		"  vault-helper/\n" +
+		"    main.go\n" +
+		"  vault/\n" +
+		"    main.go\n"
+	assert.Equal(t, expected, g.stdout.(*bytes.Buffer).String())
+	assert.Empty(t, *commands)
+	_, err := os.Stat(out)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestGenerator_RefuseToOverwriteNonGeneratedDir(t *testing.T) {
+	stubCommands(t)
+	out := t.TempDir()
+	mainGo := filepath.Join(out, "main.go")
+	require.NoError(t, os.WriteFile(mainGo, []byte("package main\n"), 0644))
+	err := testGenerator(out, "vault").run()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "refusing to overwrite")
+	content, err := os.ReadFile(mainGo)
+	require.NoError(t, err)
+	assert.Equal(t, "package main\n", string(content))
+}
+
+func TestGenerator_RegenerateRemovesDroppedShims(t *testing.T) {
+	commands := stubCommands(t)
+	out := t.TempDir()
+	require.NoError(t, testGenerator(out, "vault", "vault-helper").run())
+	require.NoError(t, os.WriteFile(filepath.Join(out, "go.sum"), []byte("sum"), 0644))
+	*commands = nil
+	require.NoError(t, testGenerator(out, "vault").run())
+	_, err := os.Stat(filepath.Join(out, "vault-helper"))
+	assert.True(t, os.IsNotExist(err))
+	for _, f := range []string{"main.go", "vault/main.go", "go.mod", "go.sum"} {
+		_, err = os.Stat(filepath.Join(out, f))
+		assert.NoError(t, err, f)
+	}
+	// go.mod is kept, so the module isn't initialized again.
+	assert.Equal(t, []string{"go mod tidy", "go install ./..."}, *commands)
+}
+
+func TestGenerator_RegenerateKeepsOtherDirs(t *testing.T) {
+	stubCommands(t)
+	out := t.TempDir()
+	require.NoError(t, testGenerator(out, "vault", "vault-helper").run())
+	for _, dir := range []string{".git/refs/tags", "docs"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(out, dir), 0755))
+	}
+	stash := filepath.Join(out, ".git", "stash.go")
+	require.NoError(t, os.WriteFile(stash, []byte(GeneratedMarker+"\n\npackage main\n"), 0644))
+	require.NoError(t, testGenerator(out, "vault").run())
+	for _, f := range []string{".git/refs/tags", ".git/stash.go", "docs", "vault/main.go"} {
+		_, err := os.Stat(filepath.Join(out, f))
+		assert.NoError(t, err, f)
+	}
+	_, err := os.Stat(filepath.Join(out, "vault-helper"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestGenerator_TemplateDirOverridesAndExtends(t *testing.T) {
+	stubCommands(t)
+	templateDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(templateDir, "shim.go.tmpl"), []byte("package main\n\n// {{ .BinaryName }} shim\nfunc main() {}\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(templateDir, "hello.go.tmpl"), []byte("package main\n"), 0644))
+	out := t.TempDir()
+	g := testGenerator(out, "vault")
+	g.templateDir = templateDir
+	require.NoError(t, g.run())
+	shim, err := os.ReadFile(filepath.Join(out, "vault", "main.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(shim), "// vault shim")
+	hello, err := os.ReadFile(filepath.Join(out, "hello.go"))
+	require.NoError(t, err)
+	assert.Equal(t, GeneratedMarker+"\n\npackage main\n", string(hello))
+	env, err := os.ReadFile(filepath.Join(out, "main.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(env), "extraCommands")
+}
+
+func TestGenerator_TemplateClashesWithGeneratedFile(t *testing.T) {
+	templateDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(templateDir, "go.mod.tmpl"), []byte("module x\n"), 0644))
+	g := testGenerator(t.TempDir(), "vault")
+	g.templateDir = templateDir
+	err := g.run()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "clashes")
+}
+
+// TestGenerator_OutputCompiles vets the generated module against this checkout, so template
+// breakage like a missing import fails here rather than on the user's machine.
+func TestGenerator_OutputCompiles(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiles the generated module")
+	}
+	root, err := filepath.Abs("..")
+	require.NoError(t, err)
+	goSum, err := os.ReadFile(filepath.Join(root, "go.sum"))
+	require.NoError(t, err)
+	stub := gostub.Stub(&executeCommand, func(wd string, env []string, name string, args ...string) error {
+		if len(args) > 1 && args[0] == "mod" && args[1] == "init" {
+			goMod := "module " + args[2] + "\n\ngo 1.22\n\nrequire github.com/lonegunmanb/genv v0.0.0\n\nreplace github.com/lonegunmanb/genv => " + root + "\n"
+			if err := os.WriteFile(filepath.Join(wd, "go.sum"), goSum, 0644); err != nil {
+				return err
+			}
+			return os.WriteFile(filepath.Join(wd, "go.mod"), []byte(goMod), 0644)
+		}
+		return nil
+	})
+	defer stub.Reset()
+	templateDir := t.TempDir()
+	extra := `package main
+
+import (
+	"fmt"
+
+	"github.com/lonegunmanb/genv/pkg"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	extraCommands = append(extraCommands, func(env *pkg.Env) *cobra.Command {
+		return &cobra.Command{
+			Use: "hello",
+			Run: func(cmd *cobra.Command, args []string) {
+				fmt.Println("hello from", env.Name())
+			},
+		}
+	})
+}
+`
+	require.NoError(t, os.WriteFile(filepath.Join(templateDir, "hello.go.tmpl"), []byte(extra), 0644))
+	out := t.TempDir()
+	g := testGenerator(out, "vault", "vault-helper")
+	g.data.Aliases = []string{"hashicorp-vault"}
+	g.data.ReleaseUrlTemplate = "https://example.com/vaultenv/{{ .Version }}"
+	g.templateDir = templateDir
+	g.noInstall = true
+	require.NoError(t, g.run())
+
+	cmd := exec.Command("go", "vet", "./...")
+	cmd.Dir = out
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod")
+	output, err := cmd.CombinedOutput()
+	assert.NoError(t, err, string(output))
+}
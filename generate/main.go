@@ -3,326 +3,100 @@ package main
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
-	"text/template"
 
+	"github.com/lonegunmanb/genv/pkg"
 	"github.com/spf13/cobra"
 )
 
-const EnvMainTemplate = `package main
-
-import (
-	"context"
-	"fmt"
-	"os"
-	"os/signal"
-
-    "github.com/lonegunmanb/genv/pkg"
-	"github.com/spf13/cobra"
-)
-
-func main() {
-	ctx, cancel := context.WithCancel(context.Background())
-	downloadInstaller, _ := pkg.NewDownloadInstaller("{{  .DownloadUrlTemplate }}", ctx)
-	goBuildInstaller := pkg.NewGoBuildInstaller("{{ .GoBuildRepoUrl }}", "{{ .BinaryName }}", "{{ .GoBuildSubFolder }}", ctx)
-	fallbackInstaller := pkg.NewFallbackInstaller(downloadInstaller, goBuildInstaller)
-	var homeDir string
-	var err error
-	if homeDir = os.Getenv("{{ .UpperName }}_HOME_DIR"); homeDir != "" {
-		err = os.MkdirAll(homeDir, os.ModePerm)
-		if err != nil {
-			panic(err.Error())
-		}
-	} else {
-		homeDir, err = os.UserHomeDir()
-		if err != nil {
-			panic(err.Error())
-		}
-	}
-	env := pkg.NewEnv(homeDir, "{{ .Name }}", "{{ .BinaryName }}", fallbackInstaller)
-
-	// Listen for interrupt signal (Ctrl + C) and cancel the context when received
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-	go func() {
-		for range c {
-			cancel()
-		}
-	}()
-
-	var rootCmd = &cobra.Command{Use: "{{ .Name }}"}
-
-	var cmdInstall = &cobra.Command{
-		Use:   "install [version]",
-		Short: "Install a specific version",
-		Args:  cobra.ExactArgs(1),
-		RunE: func(cmd *cobra.Command, args []string) error {
-			version := args[0]
-			fmt.Printf("Installing version: %s\n", version)
-			return env.Install(version)
-		},
-	}
-
-	var cmdUse = &cobra.Command{
-		Use:   "use [version]",
-		Short: "Use a specific version",
-		Args:  cobra.ExactArgs(1),
-		RunE: func(cmd *cobra.Command, args []string) error {
-			version := args[0]
-			fmt.Printf("Using version: %s\n", version)
-			return env.Use(version)
-		},
-	}
-
-  var cmdBinaryPath = &cobra.Command{
-		Use:   "path",
-		Short: "Get the full path to current binary",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			path, err := env.CurrentBinaryPath()
-			if err != nil {
-				return err
-			}
-			if path == nil {
-				return fmt.Errorf("no version selected, please run use first")
-			}
-			fmt.Print(*path)
-			return nil
-		},
-	}
-
-	var cmdUninstall = &cobra.Command{
-		Use:   "uninstall [version]",
-		Short: "Uninstall a specific version",
-		Args:  cobra.ExactArgs(1),
-		RunE: func(cmd *cobra.Command, args []string) error {
-			version := args[0]
-			fmt.Printf("Uninstalling version: %s\n", version)
-			return env.Uninstall(version)
-		},
-	}
-
-	var cmdList = &cobra.Command{
-		Use:   "list",
-		Short: "List all installed versions",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			installed, err := env.ListInstalled()
-			if err != nil {
-				return err
-			}
-			for _, i := range installed {
-				fmt.Println(i)
-			}
-			return nil
-		},
-	}
-
-	rootCmd.AddCommand(cmdInstall, cmdUse, cmdUninstall, cmdList, cmdBinaryPath)
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println("Error executing command:", err)
-	}
-}
-`
-
-const DummyMainTemplate = `
-package main
-
-import (
-	"fmt"
-	"os"
-	"os/exec"
-	"runtime"
-	"strings"
-)
-
-var binaryName = "{{ .Name }}"
-
-func main() {
-	if runtime.GOOS == "windows" {
-		binaryName = fmt.Sprintf("%s.exe", binaryName)
-	}
-	// Get the command-line arguments
-	args := os.Args[1:]
-
-	// Store the output in the dst variable
-	dst, err := currentBinaryPath()
-	if err != nil {
-		os.Exit(1)
-	}
-
-	if dst == "" || strings.Contains(dst, "no version") {
-		cmd := exec.Command(binaryName, "use", defaultVersion())
-		cmd.Stdin = os.Stdin
-		cmd.Stdout = os.Stdout
-		_ = cmd.Run()
-		dst, err = currentBinaryPath()
-		if err != nil {
-			os.Exit(1)
-		}
-	}
-
-	// Create a new command with dst and the command-line arguments
-	cmd := exec.Command(dst, args...)
-
-	// Set the command's Stdin and Stdout to the main process's Stdin and Stdout
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	// Run the command and pass through exit code
-	if err := cmd.Run(); err != nil {
-		var pe *exec.ExitError
-		if errors.As(err, &pe) {
-			os.Exit(pe.ExitCode())
-		}
-		os.Stderr.WriteString(fmt.Sprintf("Error executing command but could not get exit code: %s\n", err))
-		os.Exit(1)
-	}
-}
-
-func currentBinaryPath() (string, error) {
-	// Create a new command
-	cmd := exec.Command(binaryName, "path")
-
-	// Run the command and capture the output
-	out, err := cmd.Output()
-	if err != nil {
-		fmt.Println("Error executing command:", err)
-		return "", err
-	}
-	return string(out), nil
-}
-
-func defaultVersion() string {
-	v := os.Getenv("{{ .UpperName }}_DEFAULT_VERSION")
-	if v == "" {
-		v = "latest"
-	}
-	return v
-}
-`
-
 func main() {
-	var downloadUrlTemplate, name, binaryName, gitRepo, gitSubFolder string
+	var downloadUrlTemplate, name, gitRepo, gitSubFolder, out, templateDir, releaseUrl string
+	var binaryNames, aliases []string
+	var noInstall, dryRun, release bool
+	var releaseVersion, releaseDir string
+	var platforms []string
 
 	var cmd = &cobra.Command{
 		Use:   "genv",
 		Short: "genv is a CLI tool for managing environments",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			tplt, err := template.New("main").Parse(EnvMainTemplate)
-			if err != nil {
-				return err
-			}
-			pwd, err := os.Getwd()
-			if err != nil {
-				return err
-			}
-			binaryEnvDir := filepath.Join(pwd, "..", name)
-			if _, err = os.Stat(binaryEnvDir); err == nil {
-				_ = os.RemoveAll(binaryEnvDir)
-			}
-			err = os.Mkdir(binaryEnvDir, 0755)
-			if err != nil {
-				return err
-			}
-			dst := filepath.Clean(filepath.Join(binaryEnvDir, "main.go"))
-			file, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
-			if err != nil {
-				return err
-			}
-			defer func() {
-				_ = file.Close()
-			}()
-			envData := struct {
-				DownloadUrlTemplate string
-				Name                string
-				UpperName           string
-				BinaryName          string
-				GoBuildSubFolder    string
-				GoBuildRepoUrl      string
-			}{
-				DownloadUrlTemplate: downloadUrlTemplate,
-				Name:                name,
-				UpperName:           strings.ToUpper(name),
-				BinaryName:          binaryName,
-				GoBuildRepoUrl:      gitRepo,
-				GoBuildSubFolder:    gitSubFolder,
-			}
-			err = tplt.Execute(file, envData)
-			if err != nil {
-				return err
-			}
-
-			// Parse the DummyMainTemplate
-			tplt, err = template.New("dummyMain").Parse(DummyMainTemplate)
-			if err != nil {
-				return err
-			}
-
-			// Check if the directory ../{binaryName} exists, if not create it
-			dirPath := filepath.Join(pwd, "..", binaryName)
-			if _, err = os.Stat(dirPath); err == nil {
-				_ = os.RemoveAll(dirPath)
-			}
-			err = os.Mkdir(dirPath, 0755)
-			if err != nil {
-				return err
-			}
-
-			// Open the file ../{name}/main.go in write mode
-			dst = filepath.Join(dirPath, "main.go")
-			file, err = os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
-			if err != nil {
-				return err
-			}
-			defer func() {
-				_ = file.Close()
-			}()
-
-			// Execute the template with envData and write the output to the file
-			err = tplt.Execute(file, envData)
-			if err != nil {
-				return err
-			}
-
-			err = executeCommand(binaryEnvDir, "go", "mod", "init", name)
-			if err != nil {
-				return fmt.Errorf("failed to run 'go mod init' in the env folder: %w", err)
-			}
-			err = executeCommand(binaryEnvDir, "go", "mod", "tidy")
-			if err != nil {
-				return fmt.Errorf("failed to run 'go mod tidy' in the env folder: %w", err)
-			}
-			err = executeCommand(binaryEnvDir, "go", "install")
-			if err != nil {
-				return fmt.Errorf("failed to run 'go install' in the env folder: %w", err)
-			}
-
-			// Run 'go install' in the ../{name} folder
-			err = executeCommand(dirPath, "go", "install")
-			if err != nil {
-				return fmt.Errorf("failed to run 'go install' in the ../%s folder: %w", binaryName, err)
-			}
-			return nil
+			if name == "" {
+				return fmt.Errorf("env name is required")
+			}
+			if len(binaryNames) == 0 {
+				return fmt.Errorf("at least one binary name is required")
+			}
+			if out == "" {
+				pwd, err := os.Getwd()
+				if err != nil {
+					return err
+				}
+				out = filepath.Join(pwd, "..", name)
+			}
+			g := &generator{
+				out: out,
+				data: templateData{
+					DownloadUrlTemplate: downloadUrlTemplate,
+					Name:                name,
+					UpperName:           strings.ToUpper(name),
+					BinaryName:          binaryNames[0],
+					BinaryNames:         binaryNames,
+					Aliases:             aliases,
+					GoBuildRepoUrl:      gitRepo,
+					GoBuildSubFolder:    gitSubFolder,
+					ReleaseUrlTemplate:  releaseUrl,
+				},
+				templateDir:    templateDir,
+				noInstall:      noInstall,
+				dryRun:         dryRun,
+				release:        release,
+				releaseVersion: releaseVersion,
+				releaseDir:     releaseDir,
+				platforms:      platforms,
+				stdout:         cmd.OutOrStdout(),
+			}
+			return g.run()
 		},
 	}
 
 	cmd.Flags().StringVarP(&downloadUrlTemplate, "url", "u", "", "Download URL template")
 	cmd.Flags().StringVarP(&name, "name", "n", "", "Environment name")
-	cmd.Flags().StringVarP(&binaryName, "binary", "b", "", "Binary name")
+	cmd.Flags().StringSliceVarP(&binaryNames, "binary", "b", nil, "Binary name, repeat it or separate names with commas when a release ships several binaries, the first one is the primary binary")
+	cmd.Flags().StringSliceVar(&aliases, "alias", nil, "Extra names that identify the env in .tool-versions, besides the primary binary name")
 	cmd.Flags().StringVarP(&gitRepo, "git-repo", "", "", "Git Repository URL for Go build installer")
 	cmd.Flags().StringVarP(&gitSubFolder, "git-sub-folder", "", "", "SubFolder For Go build installer")
+	cmd.Flags().StringVarP(&out, "out", "o", "", "Output directory of the generated Go module, defaults to ../<name>")
+	cmd.Flags().StringVar(&templateDir, "template-dir", "", "Directory of *.tmpl files overriding the embedded env.go.tmpl and shim.go.tmpl, other templates are rendered next to the control plane's main.go")
+	cmd.Flags().BoolVar(&noInstall, "no-install", false, "Generate the module and tidy it without running go install, e.g. to commit the generated code")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the files that would be generated without writing anything")
+
+	cmd.Flags().BoolVar(&release, "release", false, "Cross compile the control plane and shims for --platforms and package them with checksums and a manifest instead of installing them")
+	cmd.Flags().StringVar(&releaseVersion, "release-version", "", "Version of the release, required with --release, it's reported by the control plane's --version")
+	cmd.Flags().StringVar(&releaseDir, "release-dir", "", "Directory receiving the release archives, defaults to <out>/dist")
+	cmd.Flags().StringVar(&releaseUrl, "release-url", "", "URL template of the directory a --release is published to, e.g. https://example.com/vaultenv/{{ .Version }}, the control plane's self-update reads {{ .Version }} as latest unless asked for a version")
+	cmd.Flags().StringSliceVar(&platforms, "platforms", DefaultPlatforms, "GOOS/GOARCH pairs to build the release for")
 
 	if err := cmd.Execute(); err != nil {
-		fmt.Println("Error executing command:", err)
+		os.Exit(1)
 	}
 }
 
-func executeCommand(wd string, name string, args ...string) error {
-	cmd := exec.Command(name, args...)
-	cmd.Dir = wd
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	return cmd.Run()
+type templateData struct {
+	DownloadUrlTemplate string
+	Name                string
+	UpperName           string
+	// BinaryName is the primary binary in the control plane, and the shim's own binary in a shim.
+	BinaryName       string
+	BinaryNames      []string
+	Aliases          []string
+	GoBuildSubFolder string
+	GoBuildRepoUrl   string
+	// ReleaseUrlTemplate locates the releases self-update installs, see pkg.SelfUpdater.
+	ReleaseUrlTemplate string
+}
+
+// ShimMarker is embedded in the shims, so implode only removes its own shims.
+func (d templateData) ShimMarker() string {
+	return pkg.ShimMarker(d.Name)
 }
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/lonegunmanb/genv/pkg"
+)
+
+// DefaultPlatforms are the GOOS/GOARCH pairs a release is built for unless --platforms is set.
+var DefaultPlatforms = []string{"linux/amd64", "linux/arm64", "darwin/amd64", "darwin/arm64", "windows/amd64"}
+
+// SHA256SumsName is the checksum file written next to the archives, in the format of sha256sum.
+const SHA256SumsName = "SHA256SUMS"
+
+// buildRelease cross compiles the control plane and its shims for every platform, packages each
+// platform in one archive and writes the checksums and the release manifest into releaseDir.
+func (g *generator) buildRelease() error {
+	if g.releaseVersion == "" {
+		return fmt.Errorf("a release version is required")
+	}
+	platforms := g.platforms
+	if len(platforms) == 0 {
+		platforms = DefaultPlatforms
+	}
+	releaseDir := g.releaseDir
+	if releaseDir == "" {
+		releaseDir = filepath.Join(g.out, "dist")
+	}
+	if err := os.MkdirAll(releaseDir, 0755); err != nil {
+		return err
+	}
+	staging, err := os.MkdirTemp("", "genv-release")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.RemoveAll(staging)
+	}()
+	manifest := pkg.ReleaseManifest{
+		Name:    g.data.Name,
+		Version: g.releaseVersion,
+	}
+	ldflags := fmt.Sprintf("-s -w -X main.version=%s", g.releaseVersion)
+	for _, platform := range platforms {
+		goos, goarch, ok := strings.Cut(platform, "/")
+		if !ok || goos == "" || goarch == "" {
+			return fmt.Errorf("invalid platform %s, expected GOOS/GOARCH", platform)
+		}
+		binDir := filepath.Join(staging, goos+"_"+goarch)
+		packages := map[string]string{g.data.Name: "."}
+		for _, binaryName := range g.data.BinaryNames {
+			packages[binaryName] = "./" + binaryName
+		}
+		var binaries []string
+		for binaryName, pkgPath := range packages {
+			if goos == "windows" {
+				binaryName += ".exe"
+			}
+			env := []string{"GOOS=" + goos, "GOARCH=" + goarch, "CGO_ENABLED=0"}
+			_, _ = fmt.Fprintf(g.stdout, "Building %s for %s\n", binaryName, platform)
+			err = executeCommand(g.out, env, "go", "build", "-trimpath", "-ldflags", ldflags, "-o", filepath.Join(binDir, binaryName), pkgPath)
+			if err != nil {
+				return fmt.Errorf("failed to build %s for %s: %w", binaryName, platform, err)
+			}
+			binaries = append(binaries, binaryName)
+		}
+		sort.Strings(binaries)
+		archive := pkg.ReleaseArchiveName(g.data.Name, g.releaseVersion, goos, goarch)
+		archivePath := filepath.Join(releaseDir, archive)
+		if goos == "windows" {
+			err = writeZip(archivePath, binDir, binaries)
+		} else {
+			err = writeTarGz(archivePath, binDir, binaries)
+		}
+		if err != nil {
+			return err
+		}
+		sum, err := sha256File(archivePath)
+		if err != nil {
+			return err
+		}
+		manifest.Artifacts = append(manifest.Artifacts, pkg.ReleaseArtifact{
+			Os:     goos,
+			Arch:   goarch,
+			File:   archive,
+			Sha256: sum,
+		})
+	}
+	return writeReleaseMetadata(releaseDir, manifest)
+}
+
+func writeReleaseMetadata(releaseDir string, manifest pkg.ReleaseManifest) error {
+	artifacts := append([]pkg.ReleaseArtifact(nil), manifest.Artifacts...)
+	sort.Slice(artifacts, func(i, j int) bool {
+		return artifacts[i].File < artifacts[j].File
+	})
+	var sums strings.Builder
+	for _, a := range artifacts {
+		_, _ = fmt.Fprintf(&sums, "%s  %s\n", a.Sha256, a.File)
+	}
+	if err := os.WriteFile(filepath.Join(releaseDir, SHA256SumsName), []byte(sums.String()), 0644); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(releaseDir, pkg.ReleaseManifestName), append(content, '\n'), 0644)
+}
+
+func writeTarGz(dst, dir string, files []string) (err error) {
+	f, err := os.Create(filepath.Clean(dst))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	for _, name := range files {
+		if err = addToTar(tw, filepath.Join(dir, name), name); err != nil {
+			return err
+		}
+	}
+	if err = tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+func addToTar(tw *tar.Writer, src, name string) error {
+	f, err := os.Open(filepath.Clean(src))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	err = tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0755,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+func writeZip(dst, dir string, files []string) (err error) {
+	f, err := os.Create(filepath.Clean(dst))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	zw := zip.NewWriter(f)
+	for _, name := range files {
+		if err = addToZip(zw, filepath.Join(dir, name), name); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func addToZip(zw *zip.Writer, src, name string) error {
+	f, err := os.Open(filepath.Clean(src))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	header.Method = zip.Deflate
+	header.SetMode(0755)
+	w, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, f)
+	return err
+}
+
+func sha256File(path string) (string, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
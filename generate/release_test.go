@@ -0,0 +1,137 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/lonegunmanb/genv/pkg"
+	"github.com/prashantv/gostub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_Release(t *testing.T) {
+	var builds []string
+	stub := gostub.Stub(&executeCommand, func(wd string, env []string, name string, args ...string) error {
+		if len(args) > 1 && args[0] == "mod" && args[1] == "init" {
+			return os.WriteFile(filepath.Join(wd, "go.mod"), []byte("module "+args[2]+"\n"), 0644)
+		}
+		if args[0] != "build" {
+			return nil
+		}
+		dst := args[len(args)-2]
+		pkgPath := args[len(args)-1]
+		builds = append(builds, fmt.Sprintf("%s %s %s", strings.Join(env, " "), pkgPath, filepath.Base(dst)))
+		assert.Contains(t, args, "-s -w -X main.version=1.2.3")
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(dst, []byte("binary "+filepath.Base(dst)), 0755)
+	})
+	defer stub.Reset()
+	out := t.TempDir()
+	g := testGenerator(out, "vault", "vault-helper")
+	g.release = true
+	g.releaseVersion = "1.2.3"
+	g.platforms = []string{"linux/amd64", "windows/amd64"}
+	require.NoError(t, g.run())
+
+	sort.Strings(builds)
+	assert.Equal(t, []string{
+		"GOOS=linux GOARCH=amd64 CGO_ENABLED=0 . vaultenv",
+		"GOOS=linux GOARCH=amd64 CGO_ENABLED=0 ./vault vault",
+		"GOOS=linux GOARCH=amd64 CGO_ENABLED=0 ./vault-helper vault-helper",
+		"GOOS=windows GOARCH=amd64 CGO_ENABLED=0 . vaultenv.exe",
+		"GOOS=windows GOARCH=amd64 CGO_ENABLED=0 ./vault vault.exe",
+		"GOOS=windows GOARCH=amd64 CGO_ENABLED=0 ./vault-helper vault-helper.exe",
+	}, builds)
+
+	dist := filepath.Join(out, "dist")
+	assert.Equal(t, map[string]string{
+		"vault":        "binary vault",
+		"vault-helper": "binary vault-helper",
+		"vaultenv":     "binary vaultenv",
+	}, readTarGz(t, filepath.Join(dist, "vaultenv_1.2.3_linux_amd64.tar.gz")))
+	assert.Equal(t, map[string]string{
+		"vault.exe":        "binary vault.exe",
+		"vault-helper.exe": "binary vault-helper.exe",
+		"vaultenv.exe":     "binary vaultenv.exe",
+	}, readZip(t, filepath.Join(dist, "vaultenv_1.2.3_windows_amd64.zip")))
+
+	content, err := os.ReadFile(filepath.Join(dist, pkg.ReleaseManifestName))
+	require.NoError(t, err)
+	var manifest pkg.ReleaseManifest
+	require.NoError(t, json.Unmarshal(content, &manifest))
+	assert.Equal(t, "vaultenv", manifest.Name)
+	assert.Equal(t, "1.2.3", manifest.Version)
+	require.Len(t, manifest.Artifacts, 2)
+	sums, err := os.ReadFile(filepath.Join(dist, SHA256SumsName))
+	require.NoError(t, err)
+	for _, a := range manifest.Artifacts {
+		sum, err := sha256File(filepath.Join(dist, a.File))
+		require.NoError(t, err)
+		assert.Equal(t, sum, a.Sha256)
+		assert.Contains(t, string(sums), sum+"  "+a.File+"\n")
+	}
+	assert.Equal(t, "vaultenv_1.2.3_windows_amd64.zip", manifest.Artifact("windows", "amd64").File)
+}
+
+func TestGenerator_ReleaseRequiresVersion(t *testing.T) {
+	stubCommands(t)
+	g := testGenerator(t.TempDir(), "vault")
+	g.release = true
+	err := g.run()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "release version")
+}
+
+func readTarGz(t *testing.T, path string) map[string]string {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer func() {
+		_ = f.Close()
+	}()
+	gr, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	tr := tar.NewReader(gr)
+	files := make(map[string]string)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		assert.Equal(t, int64(0755), h.Mode)
+		content, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		files[h.Name] = string(content)
+	}
+	return files
+}
+
+func readZip(t *testing.T, path string) map[string]string {
+	zr, err := zip.OpenReader(path)
+	require.NoError(t, err)
+	defer func() {
+		_ = zr.Close()
+	}()
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		_ = rc.Close()
+		files[f.Name] = string(content)
+	}
+	return files
+}
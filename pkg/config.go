@@ -0,0 +1,315 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileName is the per-user config file of a control plane, see ConfigPath.
+const ConfigFileName = "config.yaml"
+
+// Installers that can be listed in Config.InstallerOrder.
+const (
+	DownloadInstallerName = "download"
+	GoBuildInstallerName  = "go-build"
+)
+
+// ConfigKeys lists the settings of a Config by their yaml key.
+var ConfigKeys = []string{"default_version", "mirrors", "auto_install", "proxy", "ca_bundle", "netrc", "headers", "connect_timeout", "read_timeout", "download_timeout", "retries", "log_level", "installer_order"}
+
+// Config holds the per-user defaults of a control plane. Env vars override the file, see
+// ApplyEnv, and command line flags override both.
+type Config struct {
+	// DefaultVersion is used by the shim when no version is selected or pinned.
+	DefaultVersion string `yaml:"default_version,omitempty"`
+	// Mirrors are download url templates tried, in order, before the built-in one.
+	Mirrors []string `yaml:"mirrors,omitempty"`
+	// AutoInstall installs a pinned version that's missing when the shim runs.
+	AutoInstall bool `yaml:"auto_install,omitempty"`
+	// Proxy is the HTTP(S) proxy used for downloads, git clones and go builds, see SetProxyEnv.
+	Proxy string `yaml:"proxy,omitempty"`
+	// CABundle is a PEM file of extra certificates trusted for downloads.
+	CABundle string `yaml:"ca_bundle,omitempty"`
+	// Netrc is the netrc file download credentials are read from instead of ~/.netrc.
+	Netrc string `yaml:"netrc,omitempty"`
+	// Headers are sent with every download, each one is "Name: value" where the value is a
+	// template that can read env vars, see WithHeader.
+	Headers []string `yaml:"headers,omitempty"`
+	// ConnectTimeout, ReadTimeout and DownloadTimeout are durations like 30s, see
+	// WithConnectTimeout, WithReadTimeout and WithTimeout.
+	ConnectTimeout  string `yaml:"connect_timeout,omitempty"`
+	ReadTimeout     string `yaml:"read_timeout,omitempty"`
+	DownloadTimeout string `yaml:"download_timeout,omitempty"`
+	// Retries is how many times a failed download is retried, nil keeps the default.
+	Retries  *int   `yaml:"retries,omitempty"`
+	LogLevel string `yaml:"log_level,omitempty"`
+	// InstallerOrder lists the installers to try, by default download then go-build.
+	InstallerOrder []string `yaml:"installer_order,omitempty"`
+}
+
+// ConfigPath returns $XDG_CONFIG_HOME/<name>/config.yaml.
+func ConfigPath(name string) (string, error) {
+	configHome, err := ConfigHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configHome, name, ConfigFileName), nil
+}
+
+// LoadConfig reads the config file at path, a missing file is an empty config.
+func LoadConfig(path string) (*Config, error) {
+	content, err := afero.ReadFile(Fs, path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	if err = yaml.Unmarshal(content, &c); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	if err = c.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Save writes the config to path.
+func (c *Config) Save(path string) error {
+	content, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, content, 0644)
+}
+
+// ApplyEnv overrides the settings with the env vars named after the upper cased prefix and key,
+// like VAULTENV_DEFAULT_VERSION. Empty env vars are ignored.
+func (c *Config) ApplyEnv(prefix string) error {
+	for _, key := range ConfigKeys {
+		name := strings.ToUpper(prefix + "_" + key)
+		if value := os.Getenv(name); value != "" {
+			if err := c.Set(key, value); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Get returns the setting as a string, lists are comma separated.
+func (c *Config) Get(key string) (string, error) {
+	switch key {
+	case "default_version":
+		return c.DefaultVersion, nil
+	case "mirrors":
+		return strings.Join(c.Mirrors, ","), nil
+	case "auto_install":
+		return strconv.FormatBool(c.AutoInstall), nil
+	case "proxy":
+		return c.Proxy, nil
+	case "ca_bundle":
+		return c.CABundle, nil
+	case "netrc":
+		return c.Netrc, nil
+	case "headers":
+		return strings.Join(c.Headers, ","), nil
+	case "connect_timeout":
+		return c.ConnectTimeout, nil
+	case "read_timeout":
+		return c.ReadTimeout, nil
+	case "download_timeout":
+		return c.DownloadTimeout, nil
+	case "retries":
+		if c.Retries == nil {
+			return "", nil
+		}
+		return strconv.Itoa(*c.Retries), nil
+	case "log_level":
+		return c.LogLevel, nil
+	case "installer_order":
+		return strings.Join(c.InstallerOrder, ","), nil
+	}
+	return "", unknownConfigKey(key)
+}
+
+// Set parses the setting from a string, lists are comma separated. An empty value resets it. The
+// config is left untouched when the value is invalid.
+func (c *Config) Set(key, value string) error {
+	next := *c
+	switch key {
+	case "default_version":
+		next.DefaultVersion = value
+	case "mirrors":
+		next.Mirrors = splitList(value)
+	case "auto_install":
+		next.AutoInstall = false
+		if value != "" {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid auto_install %s, expected true or false", value)
+			}
+			next.AutoInstall = b
+		}
+	case "proxy":
+		next.Proxy = value
+	case "ca_bundle":
+		next.CABundle = value
+	case "netrc":
+		next.Netrc = value
+	case "headers":
+		next.Headers = splitList(value)
+	case "connect_timeout":
+		next.ConnectTimeout = value
+	case "read_timeout":
+		next.ReadTimeout = value
+	case "download_timeout":
+		next.DownloadTimeout = value
+	case "retries":
+		next.Retries = nil
+		if value != "" {
+			retries, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid retries %s, expected a number", value)
+			}
+			next.Retries = &retries
+		}
+	case "log_level":
+		next.LogLevel = strings.ToLower(value)
+	case "installer_order":
+		next.InstallerOrder = splitList(value)
+	default:
+		return unknownConfigKey(key)
+	}
+	if err := next.validate(); err != nil {
+		return err
+	}
+	*c = next
+	return nil
+}
+
+// DownloadOptions returns the options of the download installers, headers are checked when the
+// installer is created.
+func (c *Config) DownloadOptions() []DownloadOption {
+	opts := c.TransportOptions()
+	for _, header := range c.Headers {
+		name, value, _ := strings.Cut(header, ":")
+		opts = append(opts, WithHeader(strings.TrimSpace(name), strings.TrimSpace(value)))
+	}
+	return opts
+}
+
+// TransportOptions are the DownloadOptions without the headers, which are meant for the download
+// hosts only, for requests like the self-update's.
+func (c *Config) TransportOptions() []DownloadOption {
+	var opts []DownloadOption
+	for _, timeout := range []struct {
+		value  string
+		option func(time.Duration) DownloadOption
+	}{
+		{c.ConnectTimeout, WithConnectTimeout},
+		{c.ReadTimeout, WithReadTimeout},
+		{c.DownloadTimeout, WithTimeout},
+	} {
+		// Validated when the config was loaded or set.
+		if d, err := time.ParseDuration(timeout.value); err == nil {
+			opts = append(opts, timeout.option(d))
+		}
+	}
+	if c.Retries != nil {
+		opts = append(opts, WithRetries(*c.Retries))
+	}
+	if c.CABundle != "" {
+		opts = append(opts, WithCABundle(c.CABundle))
+	}
+	if c.Netrc != "" {
+		opts = append(opts, WithNetrc(c.Netrc))
+	}
+	return opts
+}
+
+// proxyEnvVars are the env vars a proxy is read from, by our http clients as well as by git and go.
+var proxyEnvVars = []string{"HTTP_PROXY", "HTTPS_PROXY", "http_proxy", "https_proxy"}
+
+// SetProxyEnv exports Proxy as HTTP_PROXY and HTTPS_PROXY, the only way to reach the git and go
+// commands an install runs, so NO_PROXY still applies. A proxy already set in the environment
+// wins over the setting. The returned func removes what was exported, before running a binary
+// that shouldn't inherit it.
+func (c *Config) SetProxyEnv() (unset func(), err error) {
+	unset = func() {}
+	if c.Proxy == "" {
+		return unset, nil
+	}
+	for _, name := range proxyEnvVars {
+		if os.Getenv(name) != "" {
+			return unset, nil
+		}
+	}
+	exported := proxyEnvVars[:2]
+	for _, name := range exported {
+		if err = os.Setenv(name, c.Proxy); err != nil {
+			return unset, err
+		}
+	}
+	return func() {
+		for _, name := range exported {
+			_ = os.Unsetenv(name)
+		}
+	}, nil
+}
+
+func (c *Config) validate() error {
+	for key, value := range map[string]string{
+		"connect_timeout":  c.ConnectTimeout,
+		"read_timeout":     c.ReadTimeout,
+		"download_timeout": c.DownloadTimeout,
+	} {
+		if value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err != nil || d < 0 {
+			return fmt.Errorf("invalid %s %s, expected a duration like 30s", key, value)
+		}
+	}
+	if c.Retries != nil && *c.Retries < 0 {
+		return fmt.Errorf("invalid retries %d, it can't be negative", *c.Retries)
+	}
+	for _, header := range c.Headers {
+		if name, _, ok := strings.Cut(header, ":"); !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("invalid header %s, expected Name: value", header)
+		}
+	}
+	if c.LogLevel != "" && !slices.Contains(LogLevels, c.LogLevel) {
+		return fmt.Errorf("invalid log_level %s, expected one of: %s", c.LogLevel, strings.Join(LogLevels, ", "))
+	}
+	for _, installer := range c.InstallerOrder {
+		if installer != DownloadInstallerName && installer != GoBuildInstallerName {
+			return fmt.Errorf("invalid installer %s in installer_order, expected %s or %s", installer, DownloadInstallerName, GoBuildInstallerName)
+		}
+	}
+	return nil
+}
+
+func unknownConfigKey(key string) error {
+	return fmt.Errorf("unknown config key %s, expected one of: %s", key, strings.Join(ConfigKeys, ", "))
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,129 @@
+package pkg_test
+
+import (
+	"os"
+
+	"github.com/lonegunmanb/genv/pkg"
+	"github.com/spf13/afero"
+)
+
+func (d *envSuite) TestLoadConfig_MissingFileIsEmpty() {
+	cfg, err := pkg.LoadConfig("/home/user/.config/vaultenv/config.yaml")
+	d.NoError(err)
+	d.Equal(&pkg.Config{}, cfg)
+}
+
+func (d *envSuite) TestConfig_SaveAndLoad() {
+	path := "/home/user/.config/vaultenv/config.yaml"
+	zero := 0
+	cfg := &pkg.Config{}
+	d.NoError(cfg.Set("default_version", "1.6.0"))
+	d.NoError(cfg.Set("mirrors", "https://m1/{{ .Version }}, https://m2/{{ .Version }}"))
+	d.NoError(cfg.Set("auto_install", "true"))
+	d.NoError(cfg.Set("log_level", "WARN"))
+	d.NoError(cfg.Set("installer_order", "go-build,download"))
+	d.NoError(cfg.Set("headers", `Authorization: Bearer {{ env "TOKEN" }}`))
+	d.NoError(cfg.Set("read_timeout", "2m"))
+	d.NoError(cfg.Set("retries", "0"))
+	d.NoError(cfg.Save(path))
+	loaded, err := pkg.LoadConfig(path)
+	d.NoError(err)
+	d.Equal(&pkg.Config{
+		DefaultVersion: "1.6.0",
+		Mirrors:        []string{"https://m1/{{ .Version }}", "https://m2/{{ .Version }}"},
+		AutoInstall:    true,
+		LogLevel:       "warn",
+		InstallerOrder: []string{"go-build", "download"},
+		Headers:        []string{`Authorization: Bearer {{ env "TOKEN" }}`},
+		ReadTimeout:    "2m",
+		Retries:        &zero,
+	}, loaded)
+	for key, expected := range map[string]string{
+		"default_version": "1.6.0",
+		"mirrors":         "https://m1/{{ .Version }},https://m2/{{ .Version }}",
+		"auto_install":    "true",
+		"proxy":           "",
+		"log_level":       "warn",
+		"installer_order": "go-build,download",
+		"headers":         `Authorization: Bearer {{ env "TOKEN" }}`,
+		"ca_bundle":       "",
+		"read_timeout":    "2m",
+		"connect_timeout": "",
+		"retries":         "0",
+	} {
+		value, err := loaded.Get(key)
+		d.NoError(err)
+		d.Equal(expected, value, key)
+	}
+	d.NoError(loaded.Set("mirrors", ""))
+	d.Nil(loaded.Mirrors, "an empty value resets the setting")
+	d.NoError(loaded.Set("retries", ""))
+	d.Nil(loaded.Retries)
+}
+
+func (d *envSuite) TestConfig_Invalid() {
+	cfg := &pkg.Config{}
+	d.ErrorContains(cfg.Set("colour", "blue"), "unknown config key colour")
+	_, err := cfg.Get("colour")
+	d.ErrorContains(err, "unknown config key colour")
+	d.ErrorContains(cfg.Set("auto_install", "maybe"), "invalid auto_install")
+	d.ErrorContains(cfg.Set("log_level", "loud"), "invalid log_level")
+	d.ErrorContains(cfg.Set("installer_order", "download,curl"), "invalid installer curl")
+	d.ErrorContains(cfg.Set("headers", "Bearer token"), "invalid header Bearer token")
+	d.ErrorContains(cfg.Set("read_timeout", "soon"), "invalid read_timeout soon")
+	d.ErrorContains(cfg.Set("download_timeout", "-1s"), "invalid download_timeout")
+	d.ErrorContains(cfg.Set("retries", "many"), "invalid retries many")
+	d.ErrorContains(cfg.Set("retries", "-1"), "invalid retries -1")
+
+	d.files(map[string][]byte{
+		"/broken.yaml":  []byte("mirrors: [unterminated"),
+		"/invalid.yaml": []byte("log_level: loud\n"),
+	})
+	_, err = pkg.LoadConfig("/broken.yaml")
+	d.ErrorContains(err, "invalid config /broken.yaml")
+	_, err = pkg.LoadConfig("/invalid.yaml")
+	d.ErrorContains(err, "invalid log_level")
+}
+
+func (d *envSuite) TestConfig_ApplyEnv() {
+	d.NoError(afero.WriteFile(d.mockFs, "/config.yaml", []byte("default_version: 1.6.0\nproxy: http://file-proxy\n"), 0644))
+	d.T().Setenv("VAULTENV_DEFAULT_VERSION", "1.7.0")
+	d.T().Setenv("VAULTENV_AUTO_INSTALL", "1")
+	d.T().Setenv("VAULTENV_PROXY", "")
+	cfg, err := pkg.LoadConfig("/config.yaml")
+	d.NoError(err)
+	d.NoError(cfg.ApplyEnv("vaultenv"))
+	d.Equal("1.7.0", cfg.DefaultVersion)
+	d.True(cfg.AutoInstall)
+	d.Equal("http://file-proxy", cfg.Proxy, "empty env vars don't override the file")
+
+	d.T().Setenv("VAULTENV_LOG_LEVEL", "loud")
+	d.ErrorContains(cfg.ApplyEnv("VAULTENV"), "VAULTENV_LOG_LEVEL")
+}
+
+func (d *envSuite) TestConfig_SetProxyEnv() {
+	for _, name := range []string{"HTTP_PROXY", "HTTPS_PROXY", "http_proxy", "https_proxy"} {
+		d.T().Setenv(name, "")
+	}
+	cfg := &pkg.Config{Proxy: "http://config-proxy:3128"}
+	unset, err := cfg.SetProxyEnv()
+	d.NoError(err)
+	d.Equal("http://config-proxy:3128", os.Getenv("HTTP_PROXY"))
+	d.Equal("http://config-proxy:3128", os.Getenv("HTTPS_PROXY"))
+	unset()
+	d.Empty(os.Getenv("HTTP_PROXY"))
+	d.Empty(os.Getenv("HTTPS_PROXY"))
+
+	d.T().Setenv("https_proxy", "http://user-proxy:8080")
+	_, err = cfg.SetProxyEnv()
+	d.NoError(err)
+	d.Empty(os.Getenv("HTTP_PROXY"), "the user's proxy wins over the setting")
+	d.Empty(os.Getenv("HTTPS_PROXY"))
+}
+
+func (d *envSuite) TestConfigPath() {
+	d.T().Setenv("XDG_CONFIG_HOME", "/xdg/config")
+	path, err := pkg.ConfigPath("vaultenv")
+	d.NoError(err)
+	d.Equal("/xdg/config/vaultenv/config.yaml", path)
+}
@@ -3,31 +3,19 @@ package pkg
 import (
 	"bytes"
 	"context"
-	"fmt"
+	"net/http"
 	"path/filepath"
 	"runtime"
 	"text/template"
-	"time"
 
 	getter2 "github.com/hashicorp/go-getter/v2"
 	"github.com/spf13/afero"
 )
 
-var _ Installer = &DownloadInstaller{}
+var _ InfoInstaller = &DownloadInstaller{}
 var Fs = afero.NewOsFs()
 var Os = runtime.GOOS
 
-func init() {
-	for _, g := range getter2.Getters {
-		h, ok := g.(*getter2.HttpGetter)
-		if !ok {
-			continue
-		}
-		h.HeadFirstTimeout = time.Duration(0)
-		h.ReadTimeout = time.Duration(0)
-	}
-}
-
 type downloadArgument struct {
 	Version string
 	Os      string
@@ -36,6 +24,8 @@ type downloadArgument struct {
 
 type DownloadInstaller struct {
 	downloadUrlTemplate string
+	opts                httpOptions
+	httpClient          *http.Client
 
 	ctx context.Context
 }
@@ -44,33 +34,65 @@ func (d *DownloadInstaller) Available() bool {
 	return true
 }
 
-func NewDownloadInstaller(downloadUrlTemplate string, ctx context.Context) (*DownloadInstaller, error) {
+func NewDownloadInstaller(downloadUrlTemplate string, ctx context.Context, opts ...DownloadOption) (*DownloadInstaller, error) {
 	if ctx == nil {
 		ctx = context.TODO()
 	}
 	d := &DownloadInstaller{
 		downloadUrlTemplate: downloadUrlTemplate,
+		opts:                defaultHttpOptions(),
 		ctx:                 ctx,
 	}
 	if err := d.validUrlTemplate(downloadUrlTemplate); err != nil {
 		return nil, err
 	}
+	for _, opt := range opts {
+		opt(&d.opts)
+	}
+	if err := d.opts.validate(); err != nil {
+		return nil, err
+	}
+	httpClient, err := d.opts.client()
+	if err != nil {
+		return nil, err
+	}
+	d.httpClient = httpClient
 	return d, nil
 }
 
 func (d *DownloadInstaller) Install(version string, dstPath string) error {
-	fmt.Printf("Downloading %s\n", d.DownloadUrl(version))
-	_, err := getter2.DefaultClient.Get(d.ctx, &getter2.Request{
-		Src:             d.DownloadUrl(version),
+	_, err := d.InstallWithInfo(version, dstPath)
+	return err
+}
+
+func (d *DownloadInstaller) InstallWithInfo(version string, dstPath string) (*InstallInfo, error) {
+	url := d.DownloadUrl(version)
+	infof("Downloading %s\n", url)
+	client, err := d.opts.getterClient(d.httpClient, url)
+	if err != nil {
+		return nil, err
+	}
+	ctx := d.ctx
+	if d.opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.opts.timeout)
+		defer cancel()
+	}
+	_, err = client.Get(ctx, &getter2.Request{
+		Src:             url,
 		Dst:             filepath.Dir(dstPath),
 		GetMode:         getter2.ModeAny,
 		Copy:            true,
 		DisableSymlinks: true,
 	})
 	if err != nil {
-		fmt.Printf("Failed to download %s: %s\n", d.DownloadUrl(version), err.Error())
+		warnf("Failed to download %s: %s\n", url, err.Error())
+		return nil, err
 	}
-	return err
+	return &InstallInfo{
+		Installer: "download",
+		Source:    url,
+	}, nil
 }
 
 func (d *DownloadInstaller) DownloadUrl(version string) string {
@@ -5,29 +5,61 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/spf13/afero"
 	"github.com/xianic/fslock"
 )
 
 type Env struct {
-	homeDir    string
-	name       string
-	binaryName string
-	l          *fslock.Lock
+	homeDir     string
+	name        string
+	binaryNames []string
+	aliases     []string
+	cachePath   string
+	l           *fslock.Lock
 	Installer
 }
 
 func NewEnv(homeDir, name, binaryName string, installer Installer) *Env {
 	return &Env{
-		homeDir:    homeDir,
-		name:       name,
-		binaryName: binaryName,
-		Installer:  installer,
+		homeDir:     homeDir,
+		name:        name,
+		binaryNames: []string{binaryName},
+		Installer:   installer,
 	}
 }
 
+// NewMultiBinaryEnv creates an env whose versions ship several binaries, like go and gofmt. All
+// binaries share one version directory, the first one is the primary binary that the installer
+// is asked for and whose presence marks a version as installed, so binaryNames can't be empty.
+func NewMultiBinaryEnv(homeDir, name string, binaryNames []string, installer Installer) (*Env, error) {
+	if len(binaryNames) == 0 {
+		return nil, fmt.Errorf("env %s needs at least one binary", name)
+	}
+	for _, binaryName := range binaryNames {
+		if binaryName == "" {
+			return nil, fmt.Errorf("env %s has an empty binary name", name)
+		}
+	}
+	return &Env{
+		homeDir:     homeDir,
+		name:        name,
+		binaryNames: binaryNames,
+		Installer:   installer,
+	}, nil
+}
+
+// SetCacheDir moves the cache, like the remote versions list, out of the env directory, for
+// example into $XDG_CACHE_HOME. Cached files can be deleted at any time.
+func (env *Env) SetCacheDir(dir string) {
+	env.cachePath = dir
+}
+
 type Installer interface {
 	Install(version string, dstPath string) error
 	Available() bool
@@ -41,8 +73,14 @@ func (env *Env) Use(version string) error {
 	defer func() {
 		_ = env.unlock()
 	}()
-	profilePath := env.profilePath()
 	profile, err := env.profile()
+	if errors.Is(err, ErrCorruptProfile) {
+		// Use overwrites the profile anyway, keep the corrupt one aside for inspection.
+		if err = Fs.Rename(env.profilePath(), env.profilePath()+".corrupt"); err != nil {
+			return err
+		}
+		profile, err = nil, nil
+	}
 	if err != nil {
 		return err
 	}
@@ -53,25 +91,45 @@ func (env *Env) Use(version string) error {
 	if version == "" {
 		pv = nil
 	}
+	profile.SchemaVersion = ProfileSchemaVersion
 	profile.Version = pv
 	profileContent, err := json.Marshal(profile)
 	if err != nil {
 		return err
 	}
 	if version == "" {
-		return afero.WriteFile(Fs, profilePath, profileContent, os.ModePerm)
+		return writeFileAtomic(env.profilePath(), profileContent, 0644)
 	}
 	installed, err := env.Installed(version)
 	if err != nil {
 		return err
 	}
 	if !installed {
-		err = env.Install(version)
+		err = env.install(version)
 		if err != nil {
 			return err
 		}
 	}
-	return afero.WriteFile(Fs, profilePath, profileContent, os.ModePerm)
+	return writeFileAtomic(env.profilePath(), profileContent, 0644)
+}
+
+// Exec runs the given version once with args, installing it first when it's missing. Stdio is
+// passed through, a non-zero exit code is returned as *exec.ExitError. The profile is left untouched.
+func (env *Env) Exec(version string, args []string) error {
+	installed, err := env.Installed(version)
+	if err != nil {
+		return err
+	}
+	if !installed {
+		if err = env.Install(version); err != nil {
+			return err
+		}
+	}
+	cmd := exec.Command(env.binaryPath(version), args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
 }
 
 func (env *Env) Installed(version string) (bool, error) {
@@ -83,17 +141,87 @@ func (env *Env) Installed(version string) (bool, error) {
 	return b, nil
 }
 
+// Install installs the given version unless it's installed already, it holds the env lock so
+// concurrent installs, even from other processes, don't step on each other.
 func (env *Env) Install(version string) error {
 	installed, err := env.Installed(version)
+	if err != nil || installed {
+		return err
+	}
+	if err = env.lock(); err != nil {
+		return err
+	}
+	defer func() {
+		_ = env.unlock()
+	}()
+	return env.install(version)
+}
+
+func (env *Env) install(version string) error {
+	// Another process may have installed it while we were waiting for the lock.
+	installed, err := env.Installed(version)
+	if err != nil || installed {
+		return err
+	}
+	binaryPath := env.binaryPath(version)
+	info, err := installWithInfo(env.Installer, version, binaryPath)
 	if err != nil {
 		return err
 	}
-	if installed {
-		return nil
+	if err = env.collectBinaries(version); err != nil {
+		// The primary binary alone would pass for an installed version.
+		_ = Fs.RemoveAll(env.versionDir(version))
+		return err
+	}
+	info.Version = version
+	info.InstalledAt = time.Now()
+	if info.Sha256, err = fileSha256(binaryPath); err != nil {
+		return err
+	}
+	infoContent, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	return afero.WriteFile(Fs, env.infoPath(version), infoContent, 0644)
+}
+
+// collectBinaries checks the installer delivered every binary of the version, it only asks for
+// the primary one. Binaries left in a sub directory, like an archive's, are moved next to it.
+func (env *Env) collectBinaries(version string) error {
+	for _, binaryName := range env.binaryNames {
+		if err := findBinary(env.versionDir(version), env.versionBinaryPath(version, binaryName)); err != nil {
+			return fmt.Errorf("version %s of %s is incomplete: %w", version, env.name, err)
+		}
 	}
-	return env.Installer.Install(version, env.binaryPath(version))
+	return nil
+}
+
+// Info returns the install information recorded for the given version, nil if the version
+// was installed before such information was recorded.
+func (env *Env) Info(version string) (*InstallInfo, error) {
+	installed, err := env.Installed(version)
+	if err != nil {
+		return nil, err
+	}
+	if !installed {
+		return nil, fmt.Errorf("version %s is not installed", version)
+	}
+	infoContent, err := afero.ReadFile(Fs, env.infoPath(version))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var info InstallInfo
+	if err = json.Unmarshal(infoContent, &info); err != nil {
+		return nil, fmt.Errorf("invalid install info %s: %w", env.infoPath(version), err)
+	}
+	return &info, nil
 }
 
+// ListInstalled returns installed versions sorted by SortVersions, directories that don't
+// contain the binary, like staging or cache directories, are ignored.
 func (env *Env) ListInstalled() ([]string, error) {
 	var installed []string
 	dir, err := afero.ReadDir(Fs, filepath.Dir(env.profilePath()))
@@ -104,36 +232,65 @@ func (env *Env) ListInstalled() ([]string, error) {
 		return nil, err
 	}
 	for _, info := range dir {
-		if info.IsDir() {
+		if !info.IsDir() {
+			continue
+		}
+		ok, err := env.Installed(info.Name())
+		if err != nil {
+			return nil, err
+		}
+		if ok {
 			installed = append(installed, info.Name())
 		}
 	}
+	SortVersions(installed)
 	return installed, nil
 }
 
+// ListInstalledMatching returns installed versions that satisfy the semver constraint,
+// an empty constraint matches all installed versions.
+func (env *Env) ListInstalledMatching(constraint string) ([]string, error) {
+	installed, err := env.ListInstalled()
+	if err != nil || constraint == "" {
+		return installed, err
+	}
+	return FilterVersions(installed, constraint)
+}
+
 func (env *Env) Name() string {
 	return env.name
 }
 
+// BinaryName returns the primary binary name.
 func (env *Env) BinaryName() string {
-	return env.binaryName
+	return env.binaryNames[0]
 }
 
-func (env *Env) CurrentBinaryPath() (*string, error) {
-	ver, err := env.CurrentVersion()
+func (env *Env) BinaryNames() []string {
+	return env.binaryNames
+}
+
+// CurrentBinaryPath returns the path of the given binary in the version in effect for the working
+// directory, see ResolveVersion. An empty binaryName means the primary binary.
+func (env *Env) CurrentBinaryPath(binaryName string) (*string, error) {
+	if binaryName == "" {
+		binaryName = env.BinaryName()
+	}
+	if !slices.Contains(env.binaryNames, binaryName) {
+		return nil, fmt.Errorf("unknown binary %s, expected one of: %s", binaryName, strings.Join(env.binaryNames, ", "))
+	}
+	pwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	ver, err := env.ResolveVersion(pwd)
 	if err != nil {
 		return nil, err
 	}
 	if ver == nil {
 		return nil, nil
 	}
-
-	binaryName := env.binaryName
-	if Os == "windows" {
-		binaryName = fmt.Sprintf("%s.exe", binaryName)
-	}
-
-	p := filepath.Join(env.homeDir, env.name, *ver, binaryName)
+	p := env.versionBinaryPath(*ver, binaryName)
 	return &p, nil
 }
 
@@ -171,11 +328,26 @@ func (env *Env) Uninstall(version string) error {
 }
 
 func (env *Env) binaryPath(version string) string {
-	binaryName := env.binaryName
+	return env.versionBinaryPath(version, env.BinaryName())
+}
+
+func (env *Env) versionBinaryPath(version, binaryName string) string {
 	if Os == "windows" {
 		binaryName = fmt.Sprintf("%s.exe", binaryName)
 	}
-	return filepath.Join(env.homeDir, env.name, version, binaryName)
+	return filepath.Join(env.versionDir(version), binaryName)
+}
+
+func (env *Env) versionDir(version string) string {
+	return filepath.Join(env.homeDir, env.name, version)
+}
+
+func (env *Env) infoPath(version string) string {
+	return filepath.Join(env.versionDir(version), ".genv.json")
+}
+
+func (env *Env) usagePath(version string) string {
+	return filepath.Join(env.versionDir(version), ".usage")
 }
 
 func (env *Env) profile() (*Profile, error) {
@@ -190,9 +362,11 @@ func (env *Env) profile() (*Profile, error) {
 	if err != nil {
 		return nil, err
 	}
-	var profile Profile
-	_ = json.Unmarshal(profileContent, &profile)
-	return &profile, nil
+	profile, err := parseProfile(profileContent)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", env.profilePath(), err)
+	}
+	return profile, nil
 }
 
 func (env *Env) profilePath() string {
@@ -1,4 +1,4 @@
-//go:generate mockgen -destination installer_mock_test.go -package pkg_test . Installer
+//go:generate mockgen -destination installer_mock_test.go -package pkg_test . Installer,RemoteLister
 package pkg_test
 
 import (
@@ -11,7 +11,9 @@ import (
 	"github.com/stretchr/testify/suite"
 	"go.uber.org/mock/gomock"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
 	"testing"
 )
 
@@ -173,7 +175,7 @@ func (d *envSuite) TestCurrentBinaryPath_Installed() {
 	})
 	err := sut.Use(version)
 	d.NoError(err)
-	actual, err := sut.CurrentBinaryPath()
+	actual, err := sut.CurrentBinaryPath("")
 	d.NoError(err)
 	d.NotNil(actual)
 	d.Equal(filepath.Join(string(filepath.Separator), "tmp", "tfenv", version, "terraform"), *actual)
@@ -189,7 +191,7 @@ func (d *envSuite) TestCurrentBinaryPath_Installed_Windows() {
 	})
 	err := sut.Use(version)
 	d.NoError(err)
-	actual, err := sut.CurrentBinaryPath()
+	actual, err := sut.CurrentBinaryPath("")
 	d.NoError(err)
 	d.NotNil(actual)
 	d.Equal(filepath.Join(string(filepath.Separator), "tmp", "tfenv", version, "terraform.exe"), *actual)
@@ -197,7 +199,7 @@ func (d *envSuite) TestCurrentBinaryPath_Installed_Windows() {
 
 func (d *envSuite) TestCurrentBinaryPath_NotInstalled() {
 	sut := pkg.NewEnv("/tmp", "tfenv", "terraform", nil)
-	actual, err := sut.CurrentBinaryPath()
+	actual, err := sut.CurrentBinaryPath("")
 	d.NoError(err)
 	d.Nil(actual)
 }
@@ -281,7 +283,31 @@ func (d *envSuite) TestListInstalled() {
 				"/tmp/tfenv/c05e704f072ce244170d80b0d7abb09c86def826/terraform": []byte("fake"),
 			},
 			expected: []string{
+				"v1.0.0",
 				"c05e704f072ce244170d80b0d7abb09c86def826",
+			},
+		},
+		{
+			desc: "semver_sorted",
+			files: map[string][]byte{
+				"/tmp/tfenv/v1.10.0/terraform": []byte("fake"),
+				"/tmp/tfenv/v1.2.0/terraform":  []byte("fake"),
+				"/tmp/tfenv/1.9.0/terraform":   []byte("fake"),
+			},
+			expected: []string{
+				"v1.2.0",
+				"1.9.0",
+				"v1.10.0",
+			},
+		},
+		{
+			desc: "ignore_directories_without_binary",
+			files: map[string][]byte{
+				"/tmp/tfenv/v1.0.0/terraform":      []byte("fake"),
+				"/tmp/tfenv/.cache/versions.json":  []byte("[]"),
+				"/tmp/tfenv/staging/terraform.zip": []byte("fake"),
+			},
+			expected: []string{
 				"v1.0.0",
 			},
 		},
@@ -302,3 +328,246 @@ func (d *envSuite) TestListInstalled() {
 		})
 	}
 }
+
+func (d *envSuite) TestInstall_ShouldRecordInstallInfo() {
+	version := "v1.0.0"
+	binaryPath := filepath.Join("/tmp", "tfenv", version, "terraform")
+	d.mockInstaller.(*MockInstaller).EXPECT().Install(version, binaryPath).DoAndReturn(func(version, dstPath string) error {
+		return afero.WriteFile(d.mockFs, dstPath, []byte("fake"), 0755)
+	}).Times(1)
+	sut := pkg.NewEnv("/tmp", "tfenv", "terraform", d.mockInstaller)
+	err := sut.Install(version)
+	d.NoError(err)
+	info, err := sut.Info(version)
+	d.NoError(err)
+	d.NotNil(info)
+	d.Equal(version, info.Version)
+	// sha256 of "fake"
+	d.Equal("b5d54c39e66671c9731b9f471e585d8262cd4f54963f0c93082d8dcf334d4c78", info.Sha256)
+	d.False(info.InstalledAt.IsZero())
+}
+
+func (d *envSuite) TestInstall_MultiBinaryMovesBinariesUp() {
+	version := "1.22.3"
+	d.mockInstaller.(*MockInstaller).EXPECT().Install(version, "/tmp/goenv/1.22.3/go").DoAndReturn(func(version, dstPath string) error {
+		d.Require().NoError(afero.WriteFile(d.mockFs, dstPath, []byte("go"), 0755))
+		return afero.WriteFile(d.mockFs, "/tmp/goenv/1.22.3/go1.22.3/bin/gofmt", []byte("gofmt"), 0644)
+	}).Times(1)
+	sut, err := pkg.NewMultiBinaryEnv("/tmp", "goenv", []string{"go", "gofmt"}, d.mockInstaller)
+	d.Require().NoError(err)
+	d.NoError(sut.Install(version))
+	info, err := d.mockFs.Stat("/tmp/goenv/1.22.3/gofmt")
+	d.Require().NoError(err)
+	d.Equal(os.FileMode(0755), info.Mode().Perm())
+}
+
+func (d *envSuite) TestInstall_MultiBinaryMissingBinary() {
+	version := "1.22.3"
+	d.mockInstaller.(*MockInstaller).EXPECT().Install(version, "/tmp/goenv/1.22.3/go").DoAndReturn(func(version, dstPath string) error {
+		return afero.WriteFile(d.mockFs, dstPath, []byte("go"), 0755)
+	}).Times(1)
+	sut, err := pkg.NewMultiBinaryEnv("/tmp", "goenv", []string{"go", "gofmt"}, d.mockInstaller)
+	d.Require().NoError(err)
+	err = sut.Install(version)
+	d.ErrorContains(err, "version 1.22.3 of goenv is incomplete: gofmt not found")
+	installed, err := sut.Installed(version)
+	d.NoError(err)
+	d.False(installed, "a version missing a binary doesn't count as installed")
+}
+
+func (d *envSuite) TestInfo_InstalledWithoutInfo() {
+	version := "v1.0.0"
+	sut := pkg.NewEnv("/tmp", "tfenv", "terraform", nil)
+	d.files(map[string][]byte{
+		fmt.Sprintf("/tmp/tfenv/%s/terraform", version): []byte("fake"),
+	})
+	info, err := sut.Info(version)
+	d.NoError(err)
+	d.Nil(info)
+}
+
+func (d *envSuite) TestInfo_NotInstalled() {
+	sut := pkg.NewEnv("/tmp", "tfenv", "terraform", nil)
+	_, err := sut.Info("v1.0.0")
+	d.Error(err)
+}
+
+func (d *envSuite) TestProfile_Legacy() {
+	sut := pkg.NewEnv("/tmp", "tfenv", "terraform", nil)
+	d.files(map[string][]byte{
+		"/tmp/tfenv/.profile.json": []byte(`{"version":"v1.0.0"}`),
+	})
+	currentVersion, err := sut.CurrentVersion()
+	d.NoError(err)
+	d.Equal("v1.0.0", *currentVersion)
+}
+
+func (d *envSuite) TestProfile_Corrupt() {
+	cases := []struct {
+		desc    string
+		content string
+	}{
+		{
+			desc:    "truncated",
+			content: `{"version":`,
+		},
+		{
+			desc:    "null",
+			content: `null`,
+		},
+		{
+			desc:    "negative_schema_version",
+			content: `{"schema_version":-1,"version":"v1.0.0"}`,
+		},
+	}
+	for _, c := range cases {
+		cc := c
+		d.Run(cc.desc, func() {
+			sut := pkg.NewEnv("/tmp", "tfenv", "terraform", nil)
+			d.files(map[string][]byte{
+				"/tmp/tfenv/.profile.json": []byte(cc.content),
+			})
+			_, err := sut.CurrentVersion()
+			d.ErrorIs(err, pkg.ErrCorruptProfile)
+		})
+	}
+}
+
+func (d *envSuite) TestProfile_NewerSchemaVersion() {
+	sut := pkg.NewEnv("/tmp", "tfenv", "terraform", nil)
+	d.files(map[string][]byte{
+		"/tmp/tfenv/.profile.json": []byte(fmt.Sprintf(`{"schema_version":%d,"version":"v1.0.0"}`, pkg.ProfileSchemaVersion+1)),
+	})
+	_, err := sut.CurrentVersion()
+	d.Error(err)
+	d.NotErrorIs(err, pkg.ErrCorruptProfile)
+}
+
+func (d *envSuite) TestUseShouldRepairCorruptProfile() {
+	version := "v1.0.0"
+	sut := pkg.NewEnv("/tmp", "tfenv", "terraform", nil)
+	profilePath := "/tmp/tfenv/.profile.json"
+	d.files(map[string][]byte{
+		profilePath: []byte(`not json`),
+		fmt.Sprintf("/tmp/tfenv/%s/terraform", version): []byte("fake"),
+	})
+	err := sut.Use(version)
+	d.NoError(err)
+	currentVersion, err := sut.CurrentVersion()
+	d.NoError(err)
+	d.Equal(version, *currentVersion)
+	backup, err := afero.ReadFile(d.mockFs, profilePath+".corrupt")
+	d.NoError(err)
+	d.Equal("not json", string(backup))
+}
+
+func (d *envSuite) TestUseShouldWriteVersionedProfileAtomically() {
+	version := "v1.0.0"
+	sut := pkg.NewEnv("/tmp", "tfenv", "terraform", nil)
+	profilePath := "/tmp/tfenv/.profile.json"
+	d.files(map[string][]byte{
+		profilePath: []byte(`{"version":"v0.1.0"}`),
+		fmt.Sprintf("/tmp/tfenv/%s/terraform", version): []byte("fake"),
+	})
+	err := sut.Use(version)
+	d.NoError(err)
+	file, err := afero.ReadFile(d.mockFs, profilePath)
+	d.NoError(err)
+	var profile pkg.Profile
+	err = json.Unmarshal(file, &profile)
+	d.NoError(err)
+	d.Equal(pkg.ProfileSchemaVersion, profile.SchemaVersion)
+	d.Equal(version, *profile.Version)
+	stat, err := d.mockFs.Stat(profilePath)
+	d.NoError(err)
+	d.Equal(os.FileMode(0644), stat.Mode().Perm())
+	entries, err := afero.ReadDir(d.mockFs, "/tmp/tfenv")
+	d.NoError(err)
+	for _, e := range entries {
+		d.NotContains(e.Name(), ".tmp")
+	}
+}
+
+func (d *envSuite) TestListInstalledMatching() {
+	d.files(map[string][]byte{
+		"/tmp/tfenv/v1.5.7/terraform":                                   []byte("fake"),
+		"/tmp/tfenv/v1.6.0/terraform":                                   []byte("fake"),
+		"/tmp/tfenv/1.7.5/terraform":                                    []byte("fake"),
+		"/tmp/tfenv/c05e704f072ce244170d80b0d7abb09c86def826/terraform": []byte("fake"),
+	})
+	sut := pkg.NewEnv("/tmp", "tfenv", "terraform", nil)
+	installed, err := sut.ListInstalledMatching(">=1.6")
+	d.NoError(err)
+	d.Equal([]string{"v1.6.0", "1.7.5"}, installed)
+	installed, err = sut.ListInstalledMatching("")
+	d.NoError(err)
+	d.Len(installed, 4)
+	_, err = sut.ListInstalledMatching("not a constraint")
+	d.Error(err)
+}
+
+func (d *envSuite) TestExec() {
+	if runtime.GOOS == "windows" {
+		d.T().Skip("fake binary is a shell script")
+	}
+	homeDir := d.T().TempDir()
+	stub := gostub.Stub(&pkg.Fs, afero.NewOsFs())
+	defer stub.Reset()
+	version := "v1.0.0"
+	binaryPath := filepath.Join(homeDir, "tfenv", version, "terraform")
+	output := filepath.Join(homeDir, "output")
+	d.mockInstaller.(*MockInstaller).EXPECT().Install(version, binaryPath).DoAndReturn(func(version, dstPath string) error {
+		d.Require().NoError(os.MkdirAll(filepath.Dir(dstPath), 0755))
+		script := fmt.Sprintf("#!/bin/sh\necho \"$@\" > %s\nexit 3\n", output)
+		return os.WriteFile(dstPath, []byte(script), 0755)
+	}).Times(1)
+	sut := pkg.NewEnv(homeDir, "tfenv", "terraform", d.mockInstaller)
+	err := sut.Exec(version, []string{"plan", "-out", "tfplan"})
+	var exitErr *exec.ExitError
+	d.Require().ErrorAs(err, &exitErr)
+	d.Equal(3, exitErr.ExitCode())
+	args, err := os.ReadFile(output)
+	d.NoError(err)
+	d.Equal("plan -out tfplan\n", string(args))
+	currentVersion, err := sut.CurrentVersion()
+	d.NoError(err)
+	d.Nil(currentVersion)
+}
+
+func (d *envSuite) TestNewMultiBinaryEnv_Invalid() {
+	for desc, binaryNames := range map[string][]string{
+		"no_binaries":  nil,
+		"empty_binary": {"go", ""},
+	} {
+		d.Run(desc, func() {
+			_, err := pkg.NewMultiBinaryEnv("/tmp", "goenv", binaryNames, nil)
+			d.Error(err)
+		})
+	}
+}
+
+func (d *envSuite) TestCurrentBinaryPath_MultiBinary() {
+	version := "1.22.3"
+	sut, err := pkg.NewMultiBinaryEnv("/tmp", "goenv", []string{"go", "gofmt"}, nil)
+	d.Require().NoError(err)
+	d.files(map[string][]byte{
+		fmt.Sprintf("/tmp/goenv/%s/go", version):    []byte("fake"),
+		fmt.Sprintf("/tmp/goenv/%s/gofmt", version): []byte("fake"),
+	})
+	err = sut.Use(version)
+	d.NoError(err)
+	for binary, expected := range map[string]string{
+		"":      "go",
+		"go":    "go",
+		"gofmt": "gofmt",
+	} {
+		actual, err := sut.CurrentBinaryPath(binary)
+		d.NoError(err)
+		d.NotNil(actual)
+		d.Equal(filepath.Join(string(filepath.Separator), "tmp", "goenv", version, expected), *actual)
+	}
+	_, err = sut.CurrentBinaryPath("terraform")
+	d.Error(err)
+	d.Equal("go", sut.BinaryName())
+	d.Equal([]string{"go", "gofmt"}, sut.BinaryNames())
+}
@@ -7,46 +7,85 @@ import (
 	"github.com/Masterminds/semver/v3"
 )
 
-var _ Installer = &fallbackInstaller{}
+var _ InfoInstaller = &fallbackInstaller{}
+var _ RemoteLister = &fallbackInstaller{}
 
+// fallbackInstaller tries its installers in order until one succeeds.
 type fallbackInstaller struct {
-	i1 Installer
-	i2 Installer
+	installers []Installer
 }
 
 func (f *fallbackInstaller) Install(version string, dstPath string) error {
-	err := f.install(f.i1, version, dstPath)
-	if err != nil {
-		return f.install(f.i2, version, dstPath)
+	_, err := f.InstallWithInfo(version, dstPath)
+	return err
+}
+
+func (f *fallbackInstaller) InstallWithInfo(version string, dstPath string) (*InstallInfo, error) {
+	var err error
+	for _, i := range f.installers {
+		var info *InstallInfo
+		if info, err = f.install(i, version, dstPath); err == nil {
+			return info, nil
+		}
 	}
-	return nil
+	return nil, err
 }
 
 func (f *fallbackInstaller) Available() bool {
-	return f.i1.Available() || f.i2.Available()
+	for _, i := range f.installers {
+		if i.Available() {
+			return true
+		}
+	}
+	return false
+}
+
+// ListRemote lists versions with the first installer that can list them.
+func (f *fallbackInstaller) ListRemote() ([]string, error) {
+	var err error = ErrNoVersionSource
+	for _, i := range f.installers {
+		lister, ok := i.(RemoteLister)
+		if !ok {
+			continue
+		}
+		var versions []string
+		if versions, err = lister.ListRemote(); err == nil {
+			return versions, nil
+		}
+	}
+	return nil, err
 }
 
 func NewFallbackInstaller(i1 Installer, i2 Installer) Installer {
 	return &fallbackInstaller{
-		i1: i1,
-		i2: i2,
+		installers: []Installer{i1, i2},
 	}
 }
 
-func (f *fallbackInstaller) install(i Installer, version string, dstPath string) error {
+// NewChainInstaller generalizes NewFallbackInstaller to any number of installers, tried in order.
+func NewChainInstaller(installers ...Installer) (Installer, error) {
+	if len(installers) == 0 {
+		return nil, fmt.Errorf("at least one installer is required")
+	}
+	return &fallbackInstaller{
+		installers: installers,
+	}, nil
+}
+
+func (f *fallbackInstaller) install(i Installer, version string, dstPath string) (*InstallInfo, error) {
 	_, err := semver.NewVersion(version)
 	isSemver := err == nil
 	if !isSemver {
-		return i.Install(version, dstPath)
+		return installWithInfo(i, version, dstPath)
 	}
-	err = i.Install(version, dstPath)
+	info, err := installWithInfo(i, version, dstPath)
 	if err == nil {
-		return nil
+		return info, nil
 	}
 	if strings.HasPrefix(version, "v") {
 		version = version[1:]
 	} else {
 		version = fmt.Sprintf("v%s", version)
 	}
-	return i.Install(version, dstPath)
+	return installWithInfo(i, version, dstPath)
 }
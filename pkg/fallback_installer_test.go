@@ -112,3 +112,48 @@ func TestFallbackInstallerWontTryToFixVersionWhenNotSemverandInstallError(t *tes
 	err := sut.Install(v, "/tmp")
 	assert.NoError(t, err)
 }
+
+func TestFallbackInstallerListRemote(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	lister := NewMockRemoteLister(ctrl)
+	lister.EXPECT().ListRemote().Times(1).Return([]string{"v1.0.0"}, nil)
+	sut := pkg.NewFallbackInstaller(NewMockInstaller(ctrl), listerInstaller{
+		MockInstaller:    NewMockInstaller(ctrl),
+		MockRemoteLister: lister,
+	})
+	versions, err := sut.(pkg.RemoteLister).ListRemote()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"v1.0.0"}, versions)
+}
+
+func TestFallbackInstallerListRemote_NoVersionSource(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	sut := pkg.NewFallbackInstaller(NewMockInstaller(ctrl), NewMockInstaller(ctrl))
+	_, err := sut.(pkg.RemoteLister).ListRemote()
+	assert.ErrorIs(t, err, pkg.ErrNoVersionSource)
+}
+
+func TestChainInstaller(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	var installers []pkg.Installer
+	for i := 0; i < 3; i++ {
+		installer := NewMockInstaller(ctrl)
+		if i < 2 {
+			// Every installer is tried once with each spelling of the version, no more.
+			installer.EXPECT().Install("1.0.0", "/tmp").Times(1).Return(fmt.Errorf("error"))
+			installer.EXPECT().Install("v1.0.0", "/tmp").Times(1).Return(fmt.Errorf("error"))
+		} else {
+			installer.EXPECT().Install("1.0.0", "/tmp").Times(1).Return(nil)
+		}
+		installers = append(installers, installer)
+	}
+	sut, err := pkg.NewChainInstaller(installers...)
+	assert.NoError(t, err)
+	assert.NoError(t, sut.Install("1.0.0", "/tmp"))
+
+	_, err = pkg.NewChainInstaller()
+	assert.Error(t, err)
+}
@@ -0,0 +1,560 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"text/template"
+
+	getter2 "github.com/hashicorp/go-getter/v2"
+	"github.com/spf13/afero"
+)
+
+var _ InfoInstaller = &GitHubReleaseInstaller{}
+var _ RemoteLister = &GitHubReleaseInstaller{}
+
+// GitHubApiUrl is the default base url of the GitHub REST API.
+const GitHubApiUrl = "https://api.github.com"
+
+// GitHubReleaseInstallerName is recorded as the installer of versions installed from GitHub releases.
+const GitHubReleaseInstallerName = "github-release"
+
+// Asset names are matched against these patterns of the running platform, case insensitive and
+// delimited by anything but a letter or a digit, unless an asset pattern is given.
+var (
+	gitHubOsPatterns = map[string]string{
+		"linux":   `linux`,
+		"darwin":  `darwin|macos|mac|osx|apple`,
+		"windows": `windows|win64|win32|win`,
+		"freebsd": `freebsd`,
+	}
+	gitHubArchPatterns = map[string]string{
+		"amd64": `amd64|x86_64|x86-64|x64|64-?bit`,
+		"arm64": `arm64|aarch64`,
+		"386":   `386|i386|i686|32-?bit`,
+		"arm":   `armv?7l?|armv?6l?|armhf|arm`,
+	}
+	// gitHubUniversalArch matches the assets built for every arch of an os, like macOS universal binaries.
+	gitHubUniversalArch = `all|universal`
+	// gitHubIgnoredAssets are signatures, checksums, SBOMs and OS packages, never the binary itself.
+	gitHubIgnoredAssets = regexp.MustCompile(`(?i)(\.(sha256|sha512|sha256sum|md5|sig|asc|pem|crt|sbom|spdx|json|txt|deb|rpm|apk|msi|pkg|dmg)$)|checksums|sha256sums`)
+	// gitHubArchiveSuffixes are extracted into the version directory, other compressed assets are
+	// decompressed into the binary itself.
+	gitHubArchiveSuffixes = []string{"tar.gz", "tgz", "tar.xz", "txz", "tar.bz2", "tbz2", "tar.zst", "tzst", "tar", "zip"}
+)
+
+// GitHubReleaseInstaller installs a binary from the assets of a GitHub release. The asset of the
+// running platform is picked by matching its name against os and arch patterns, archives are
+// extracted and the checksums asset, when there's one, is verified. Set GITHUB_TOKEN to raise the
+// API rate limit or to reach private repositories.
+type GitHubReleaseInstaller struct {
+	repo          string
+	apiUrl        string
+	assetPattern  string
+	checksumAsset string
+	opts          httpOptions
+	httpClient    *http.Client
+
+	ctx context.Context
+}
+
+type GitHubReleaseOption func(*GitHubReleaseInstaller)
+
+// WithGitHubApiUrl points the installer to another API, like a GitHub Enterprise server at
+// https://github.example.com/api/v3.
+func WithGitHubApiUrl(apiUrl string) GitHubReleaseOption {
+	return func(g *GitHubReleaseInstaller) {
+		g.apiUrl = strings.TrimSuffix(apiUrl, "/")
+	}
+}
+
+// WithAssetPattern picks the asset whose name matches the regular expression instead of guessing
+// from the platform. The pattern is a template rendered with .Version, the tag without its v
+// prefix, .Tag, .Os and .Arch, like `_{{ .Os }}_{{ .Arch }}\.tar\.gz$`.
+func WithAssetPattern(pattern string) GitHubReleaseOption {
+	return func(g *GitHubReleaseInstaller) {
+		g.assetPattern = pattern
+	}
+}
+
+// WithChecksumAsset verifies the picked asset against the sha256 found in the asset whose name
+// matches the regular expression, either a sha256sum style list like checksums.txt or a file
+// holding the sole checksum like `{{ .AssetName }}\.sha256`. The pattern is a template rendered
+// with the same fields as the asset pattern, plus .AssetName.
+func WithChecksumAsset(pattern string) GitHubReleaseOption {
+	return func(g *GitHubReleaseInstaller) {
+		g.checksumAsset = pattern
+	}
+}
+
+// WithGitHubDownloadOptions applies the http options of a DownloadInstaller, like a proxy or
+// timeouts, to the API calls and the asset downloads.
+func WithGitHubDownloadOptions(opts ...DownloadOption) GitHubReleaseOption {
+	return func(g *GitHubReleaseInstaller) {
+		for _, opt := range opts {
+			opt(&g.opts)
+		}
+	}
+}
+
+// NewGitHubReleaseInstaller creates an installer for the releases of repo, given as owner/name.
+func NewGitHubReleaseInstaller(repo string, ctx context.Context, opts ...GitHubReleaseOption) (*GitHubReleaseInstaller, error) {
+	if ctx == nil {
+		ctx = context.TODO()
+	}
+	if owner, name, ok := strings.Cut(repo, "/"); !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return nil, fmt.Errorf("invalid GitHub repository %s, expected owner/name", repo)
+	}
+	g := &GitHubReleaseInstaller{
+		repo:   repo,
+		apiUrl: GitHubApiUrl,
+		opts:   defaultHttpOptions(),
+		ctx:    ctx,
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	for _, pattern := range []string{g.assetPattern, g.checksumAsset} {
+		if _, err := renderPattern(pattern, gitHubAssetArgument{AssetName: "asset"}); err != nil {
+			return nil, err
+		}
+	}
+	if err := g.opts.validate(); err != nil {
+		return nil, err
+	}
+	httpClient, err := g.opts.client()
+	if err != nil {
+		return nil, err
+	}
+	g.httpClient = httpClient
+	return g, nil
+}
+
+type gitHubRelease struct {
+	TagName    string        `json:"tag_name"`
+	Draft      bool          `json:"draft"`
+	Prerelease bool          `json:"prerelease"`
+	Assets     []gitHubAsset `json:"assets"`
+}
+
+type gitHubAsset struct {
+	Name               string `json:"name"`
+	Url                string `json:"url"`
+	BrowserDownloadUrl string `json:"browser_download_url"`
+}
+
+type gitHubAssetArgument struct {
+	Version   string
+	Tag       string
+	Os        string
+	Arch      string
+	AssetName string
+}
+
+func (g *GitHubReleaseInstaller) Available() bool {
+	return true
+}
+
+func (g *GitHubReleaseInstaller) Install(version string, dstPath string) error {
+	_, err := g.InstallWithInfo(version, dstPath)
+	return err
+}
+
+// InstallWithInfo installs the release tagged version, with or without a v prefix, or the latest
+// release when version is "latest".
+func (g *GitHubReleaseInstaller) InstallWithInfo(version string, dstPath string) (*InstallInfo, error) {
+	release, err := g.release(version)
+	if err != nil {
+		warnf("Failed to find release %s of %s: %s\n", version, g.repo, err.Error())
+		return nil, err
+	}
+	arg := gitHubAssetArgument{
+		Version: strings.TrimPrefix(release.TagName, "v"),
+		Tag:     release.TagName,
+		Os:      Os,
+		Arch:    runtime.GOARCH,
+	}
+	asset, err := g.selectAsset(release, arg)
+	if err != nil {
+		return nil, err
+	}
+	arg.AssetName = asset.Name
+	infof("Downloading %s\n", asset.BrowserDownloadUrl)
+	tmpDir, err := os.MkdirTemp("", "genv-github")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = os.RemoveAll(tmpDir)
+	}()
+	assetPath := filepath.Join(tmpDir, filepath.Base(asset.Name))
+	sum, err := g.download(asset, assetPath)
+	if err != nil {
+		warnf("Failed to download %s: %s\n", asset.BrowserDownloadUrl, err.Error())
+		return nil, err
+	}
+	if g.checksumAsset != "" {
+		if err = g.verify(release, arg, sum); err != nil {
+			return nil, err
+		}
+	}
+	if err = installAsset(assetPath, dstPath); err != nil {
+		return nil, err
+	}
+	return &InstallInfo{
+		Installer: GitHubReleaseInstallerName,
+		Source:    asset.BrowserDownloadUrl,
+	}, nil
+}
+
+// ListRemote lists the tags of the releases that aren't drafts, prereleases included.
+func (g *GitHubReleaseInstaller) ListRemote() ([]string, error) {
+	var tags []string
+	next := fmt.Sprintf("%s/repos/%s/releases?per_page=100", g.apiUrl, g.repo)
+	for next != "" {
+		var releases []gitHubRelease
+		header, err := g.getJson(next, &releases)
+		if err != nil {
+			return nil, fmt.Errorf("cannot list releases of %s: %w", g.repo, err)
+		}
+		for _, r := range releases {
+			if !r.Draft {
+				tags = append(tags, r.TagName)
+			}
+		}
+		next = nextPage(header.Get("Link"))
+	}
+	return tags, nil
+}
+
+// LatestVersion returns the tag of the latest release, GitHub's latest excludes drafts and
+// prereleases.
+func (g *GitHubReleaseInstaller) LatestVersion() (string, error) {
+	release, err := g.release("latest")
+	if err != nil {
+		return "", err
+	}
+	return release.TagName, nil
+}
+
+func (g *GitHubReleaseInstaller) release(version string) (*gitHubRelease, error) {
+	var release gitHubRelease
+	if version == "latest" {
+		_, err := g.getJson(fmt.Sprintf("%s/repos/%s/releases/latest", g.apiUrl, g.repo), &release)
+		return &release, err
+	}
+	tags := []string{version, "v" + version}
+	if trimmed, ok := strings.CutPrefix(version, "v"); ok {
+		tags[1] = trimmed
+	}
+	var err error
+	for _, tag := range tags {
+		_, err = g.getJson(fmt.Sprintf("%s/repos/%s/releases/tags/%s", g.apiUrl, g.repo, tag), &release)
+		if !errors.Is(err, errGitHubNotFound) {
+			return &release, err
+		}
+	}
+	return nil, fmt.Errorf("%s has no release %s", g.repo, version)
+}
+
+// selectAsset picks the asset matching the asset pattern, or the only one named after the running
+// os and arch. An os asset without arch, like a macOS universal binary, is the fallback.
+func (g *GitHubReleaseInstaller) selectAsset(release *gitHubRelease, arg gitHubAssetArgument) (*gitHubAsset, error) {
+	var matchers []func(name string) bool
+	if g.assetPattern != "" {
+		pattern, err := renderPattern(g.assetPattern, arg)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, pattern.MatchString)
+	} else {
+		osRe, archRe := platformPatterns(arg.Os, arg.Arch)
+		universalRe := delimited(gitHubUniversalArch)
+		matchers = append(matchers,
+			func(name string) bool {
+				return osRe.MatchString(name) && archRe.MatchString(name) && !gitHubIgnoredAssets.MatchString(name)
+			},
+			func(name string) bool {
+				return osRe.MatchString(name) && universalRe.MatchString(name) && !gitHubIgnoredAssets.MatchString(name)
+			})
+	}
+	for _, match := range matchers {
+		var matches []*gitHubAsset
+		for i, asset := range release.Assets {
+			if match(asset.Name) {
+				matches = append(matches, &release.Assets[i])
+			}
+		}
+		switch len(matches) {
+		case 0:
+			continue
+		case 1:
+			return matches[0], nil
+		default:
+			var names []string
+			for _, m := range matches {
+				names = append(names, m.Name)
+			}
+			return nil, fmt.Errorf("several assets of %s %s match %s/%s: %s, pick one with an asset pattern", g.repo, release.TagName, arg.Os, arg.Arch, strings.Join(names, ", "))
+		}
+	}
+	return nil, fmt.Errorf("no asset of %s %s matches %s/%s", g.repo, release.TagName, arg.Os, arg.Arch)
+}
+
+// verify compares sum with the checksum of the asset listed in the checksums asset.
+func (g *GitHubReleaseInstaller) verify(release *gitHubRelease, arg gitHubAssetArgument, sum string) error {
+	pattern, err := renderPattern(g.checksumAsset, arg)
+	if err != nil {
+		return err
+	}
+	var checksums *gitHubAsset
+	for i, asset := range release.Assets {
+		if pattern.MatchString(asset.Name) {
+			checksums = &release.Assets[i]
+			break
+		}
+	}
+	if checksums == nil {
+		return fmt.Errorf("%s %s has no checksums asset matching %s", g.repo, release.TagName, pattern.String())
+	}
+	var content bytes.Buffer
+	if err = g.get(g.assetUrl(checksums), "application/octet-stream", &content); err != nil {
+		return fmt.Errorf("failed to download %s: %w", checksums.Name, err)
+	}
+	expected, err := assetChecksum(content.Bytes(), arg.AssetName)
+	if err != nil {
+		return fmt.Errorf("%s: %w", checksums.Name, err)
+	}
+	if !strings.EqualFold(expected, sum) {
+		return fmt.Errorf("checksum mismatch for %s, expected %s, got %s", arg.AssetName, expected, sum)
+	}
+	return nil
+}
+
+// download writes the asset to dst and returns its sha256.
+func (g *GitHubReleaseInstaller) download(asset *gitHubAsset, dst string) (string, error) {
+	f, err := os.Create(filepath.Clean(dst))
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	h := sha256.New()
+	if err = g.get(g.assetUrl(asset), "application/octet-stream", io.MultiWriter(f, h)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// assetUrl prefers the API url of the asset, unlike the browser download url it works for private
+// repositories.
+func (g *GitHubReleaseInstaller) assetUrl(asset *gitHubAsset) string {
+	if asset.Url != "" {
+		return asset.Url
+	}
+	return asset.BrowserDownloadUrl
+}
+
+var errGitHubNotFound = errors.New("not found")
+
+func (g *GitHubReleaseInstaller) getJson(url string, v any) (http.Header, error) {
+	var body bytes.Buffer
+	header, err := g.do(url, "application/vnd.github+json", &body)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(body.Bytes(), v); err != nil {
+		return nil, fmt.Errorf("invalid response from %s: %w", url, err)
+	}
+	return header, nil
+}
+
+func (g *GitHubReleaseInstaller) get(url, accept string, w io.Writer) error {
+	_, err := g.do(url, accept, w)
+	return err
+}
+
+func (g *GitHubReleaseInstaller) do(url, accept string, w io.Writer) (http.Header, error) {
+	ctx := g.ctx
+	if g.opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.opts.timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	header, err := g.opts.header(url)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+	req.Header.Set("Accept", accept)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	// The token isn't sent along when an asset download is redirected to another host.
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" && req.Header.Get("Authorization") == "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%s: %w", url, errGitHubNotFound)
+	case resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0",
+		resp.StatusCode == http.StatusTooManyRequests:
+		return nil, fmt.Errorf("GitHub API rate limit exceeded, set GITHUB_TOKEN to raise it")
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("failed to get %s: %s", url, resp.Status)
+	}
+	if _, err = io.Copy(w, resp.Body); err != nil {
+		return nil, err
+	}
+	return resp.Header, nil
+}
+
+// installAsset extracts an archive into the directory of dstPath, moving the binary up when it's
+// in a sub directory of the archive, or decompresses or copies any other asset to dstPath.
+func installAsset(assetPath, dstPath string) error {
+	name := strings.ToLower(filepath.Base(assetPath))
+	dir := filepath.Dir(dstPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, suffix := range gitHubArchiveSuffixes {
+		if strings.HasSuffix(name, "."+suffix) {
+			if err := getter2.Decompressors[suffix].Decompress(dir, assetPath, true, 0); err != nil {
+				return fmt.Errorf("failed to extract %s: %w", filepath.Base(assetPath), err)
+			}
+			return findBinary(dir, dstPath)
+		}
+	}
+	for _, suffix := range []string{"gz", "bz2", "xz", "zst"} {
+		if strings.HasSuffix(name, "."+suffix) {
+			if err := getter2.Decompressors[suffix].Decompress(dstPath, assetPath, false, 0); err != nil {
+				return fmt.Errorf("failed to decompress %s: %w", filepath.Base(assetPath), err)
+			}
+			return os.Chmod(dstPath, 0755)
+		}
+	}
+	content, err := os.ReadFile(filepath.Clean(assetPath))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dstPath, content, 0755)
+}
+
+// findBinary moves the file named like dstPath found under dir to dstPath, unless it's there
+// already. Env.Install calls it for the other binaries of a version, an archive may keep them all
+// in a sub directory.
+func findBinary(dir, dstPath string) error {
+	if _, err := Fs.Stat(dstPath); err == nil {
+		return Fs.Chmod(dstPath, 0755)
+	}
+	var found string
+	err := afero.Walk(Fs, dir, func(p string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if found == "" && !info.IsDir() && info.Name() == filepath.Base(dstPath) {
+			found = p
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if found == "" {
+		return fmt.Errorf("%s not found in %s", filepath.Base(dstPath), dir)
+	}
+	if err = Fs.Rename(found, dstPath); err != nil {
+		return err
+	}
+	return Fs.Chmod(dstPath, 0755)
+}
+
+// assetChecksum finds the sha256 of asset in a sha256sum style list, or returns the sole checksum
+// of a file that holds nothing else.
+func assetChecksum(content []byte, asset string) (string, error) {
+	var lines [][]string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) > 0 {
+			lines = append(lines, fields)
+		}
+	}
+	for _, fields := range lines {
+		if len(fields) >= 2 && strings.TrimPrefix(fields[1], "*") == asset {
+			return fields[0], nil
+		}
+	}
+	if len(lines) == 1 && len(lines[0]) <= 2 && len(lines[0][0]) == sha256.Size*2 {
+		return lines[0][0], nil
+	}
+	return "", fmt.Errorf("no checksum found for %s", asset)
+}
+
+// platformPatterns returns the patterns that match the given os and arch in a file name.
+func platformPatterns(goos, goarch string) (*regexp.Regexp, *regexp.Regexp) {
+	osPattern, ok := gitHubOsPatterns[goos]
+	if !ok {
+		osPattern = regexp.QuoteMeta(goos)
+	}
+	archPattern, ok := gitHubArchPatterns[goarch]
+	if !ok {
+		archPattern = regexp.QuoteMeta(goarch)
+	}
+	return delimited(osPattern), delimited(archPattern)
+}
+
+// delimited matches the alternatives of pattern as a whole word, case insensitive, letters and
+// digits can't touch it.
+func delimited(pattern string) *regexp.Regexp {
+	return regexp.MustCompile(fmt.Sprintf(`(?i)(^|[^a-z0-9])(%s)([^a-z0-9]|$)`, pattern))
+}
+
+func renderPattern(patternTemplate string, arg gitHubAssetArgument) (*regexp.Regexp, error) {
+	tplt, err := template.New("pattern").Parse(patternTemplate)
+	if err != nil {
+		return nil, err
+	}
+	var buff bytes.Buffer
+	if err = tplt.Execute(&buff, arg); err != nil {
+		return nil, err
+	}
+	pattern, err := regexp.Compile(buff.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid asset pattern %s: %w", patternTemplate, err)
+	}
+	return pattern, nil
+}
+
+// nextPage returns the url of the next page from a Link header, empty on the last page.
+func nextPage(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		url, params, ok := strings.Cut(part, ";")
+		if ok && strings.Contains(params, `rel="next"`) {
+			return strings.Trim(strings.TrimSpace(url), "<>")
+		}
+	}
+	return ""
+}
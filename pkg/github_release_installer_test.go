@@ -0,0 +1,371 @@
+package pkg_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lonegunmanb/genv/pkg"
+	"github.com/prashantv/gostub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRelease struct {
+	tag        string
+	draft      bool
+	prerelease bool
+	assets     map[string][]byte
+}
+
+// fakeGitHub stands in for the releases API of owner/tool, releases are listed newest first, two
+// per page.
+type fakeGitHub struct {
+	*httptest.Server
+	releases []fakeRelease
+
+	mu             sync.Mutex
+	authorizations []string
+}
+
+func newFakeGitHub(t *testing.T, releases ...fakeRelease) *fakeGitHub {
+	useOsFs(t)
+	stub := gostub.Stub(&pkg.Os, "linux")
+	t.Cleanup(stub.Reset)
+	g := &fakeGitHub{releases: releases}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos/owner/tool/releases", func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+		end := min(page*2, len(g.releases))
+		if end < len(g.releases) {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/repos/owner/tool/releases?per_page=2&page=%d>; rel="next", <%s/repos/owner/tool/releases?page=1>; rel="first"`, g.URL, page+1, g.URL))
+		}
+		var releases []map[string]any
+		for _, release := range g.releases[(page-1)*2 : end] {
+			releases = append(releases, g.json(release))
+		}
+		_ = json.NewEncoder(w).Encode(releases)
+	})
+	mux.HandleFunc("GET /repos/owner/tool/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		for _, release := range g.releases {
+			if !release.draft && !release.prerelease {
+				_ = json.NewEncoder(w).Encode(g.json(release))
+				return
+			}
+		}
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("GET /repos/owner/tool/releases/tags/{tag}", func(w http.ResponseWriter, r *http.Request) {
+		if release := g.release(r.PathValue("tag")); release != nil {
+			_ = json.NewEncoder(w).Encode(g.json(*release))
+			return
+		}
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("GET /repos/owner/tool/releases/assets/{tag}/{name}", func(w http.ResponseWriter, r *http.Request) {
+		release := g.release(r.PathValue("tag"))
+		if release == nil || r.Header.Get("Accept") != "application/octet-stream" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(release.assets[r.PathValue("name")])
+	})
+	g.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.mu.Lock()
+		g.authorizations = append(g.authorizations, r.Header.Get("Authorization"))
+		g.mu.Unlock()
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(g.Close)
+	return g
+}
+
+func (g *fakeGitHub) release(tag string) *fakeRelease {
+	for i := range g.releases {
+		if g.releases[i].tag == tag {
+			return &g.releases[i]
+		}
+	}
+	return nil
+}
+
+func (g *fakeGitHub) json(release fakeRelease) map[string]any {
+	var assets []map[string]any
+	for name := range release.assets {
+		assets = append(assets, map[string]any{
+			"name":                 name,
+			"url":                  fmt.Sprintf("%s/repos/owner/tool/releases/assets/%s/%s", g.URL, release.tag, name),
+			"browser_download_url": fmt.Sprintf("%s/owner/tool/releases/download/%s/%s", g.URL, release.tag, name),
+		})
+	}
+	return map[string]any{
+		"tag_name":   release.tag,
+		"draft":      release.draft,
+		"prerelease": release.prerelease,
+		"assets":     assets,
+	}
+}
+
+func (g *fakeGitHub) installer(t *testing.T, opts ...pkg.GitHubReleaseOption) *pkg.GitHubReleaseInstaller {
+	opts = append([]pkg.GitHubReleaseOption{pkg.WithGitHubApiUrl(g.URL + "/")}, opts...)
+	installer, err := pkg.NewGitHubReleaseInstaller("owner/tool", nil, opts...)
+	require.NoError(t, err)
+	return installer
+}
+
+func tarGz(t *testing.T, files map[string]string) []byte {
+	var buff bytes.Buffer
+	gw := gzip.NewWriter(&buff)
+	tw := tar.NewWriter(gw)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+	return buff.Bytes()
+}
+
+func sha256Hex(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// platformAsset names the asset of the running arch like goreleaser does.
+func platformAsset(version, suffix string) string {
+	return fmt.Sprintf("tool_%s_linux_%s%s", version, runtime.GOARCH, suffix)
+}
+
+func toolRelease(t *testing.T, tag string) fakeRelease {
+	version := strings.TrimPrefix(tag, "v")
+	archive := tarGz(t, map[string]string{
+		fmt.Sprintf("tool_%s/tool", version):      "tool " + version,
+		fmt.Sprintf("tool_%s/README.md", version): "readme",
+	})
+	return fakeRelease{
+		tag: tag,
+		assets: map[string][]byte{
+			platformAsset(version, ".tar.gz"):                 archive,
+			platformAsset(version, ".deb"):                    []byte("deb"),
+			platformAsset(version, ".tar.gz.sig"):             []byte("signature"),
+			fmt.Sprintf("tool_%s_windows_amd64.zip", version): []byte("zip"),
+			fmt.Sprintf("tool_%s_darwin_all.tar.gz", version): []byte("darwin"),
+			"checksums.txt": []byte(fmt.Sprintf("%s  %s\n%s  other.zip\n", sha256Hex(archive), platformAsset(version, ".tar.gz"), strings.Repeat("0", 64))),
+		},
+	}
+}
+
+func TestGitHubReleaseInstaller_Install(t *testing.T) {
+	g := newFakeGitHub(t, toolRelease(t, "v1.0.0"))
+	dst := filepath.Join(t.TempDir(), "1.0.0", "tool")
+	info, err := g.installer(t).InstallWithInfo("1.0.0", dst)
+	require.NoError(t, err)
+	content, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	assert.Equal(t, "tool 1.0.0", string(content), "the binary is moved out of the archive's directory")
+	assert.Equal(t, pkg.GitHubReleaseInstallerName, info.Installer)
+	assert.Equal(t, fmt.Sprintf("%s/owner/tool/releases/download/v1.0.0/%s", g.URL, platformAsset("1.0.0", ".tar.gz")), info.Source)
+}
+
+func TestGitHubReleaseInstaller_Latest(t *testing.T) {
+	rc := toolRelease(t, "v2.1.0-rc.1")
+	rc.prerelease = true
+	draft := toolRelease(t, "v3.0.0")
+	draft.draft = true
+	g := newFakeGitHub(t, draft, rc, toolRelease(t, "v2.0.0"), toolRelease(t, "v1.1.0"), toolRelease(t, "v1.0.0"))
+	sut := g.installer(t)
+
+	latest, err := sut.LatestVersion()
+	require.NoError(t, err)
+	assert.Equal(t, "v2.0.0", latest)
+	dst := filepath.Join(t.TempDir(), "latest", "tool")
+	require.NoError(t, sut.Install("latest", dst))
+	content, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	assert.Equal(t, "tool 2.0.0", string(content))
+
+	tags, err := sut.ListRemote()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v2.1.0-rc.1", "v2.0.0", "v1.1.0", "v1.0.0"}, tags, "every page is listed, drafts aren't")
+}
+
+func TestGitHubReleaseInstaller_UnknownVersion(t *testing.T) {
+	g := newFakeGitHub(t, toolRelease(t, "v1.0.0"))
+	err := g.installer(t).Install("9.9.9", filepath.Join(t.TempDir(), "tool"))
+	assert.ErrorContains(t, err, "owner/tool has no release 9.9.9")
+}
+
+func TestGitHubReleaseInstaller_Checksum(t *testing.T) {
+	release := toolRelease(t, "1.0.0")
+	g := newFakeGitHub(t, release)
+	dst := filepath.Join(t.TempDir(), "tool")
+	require.NoError(t, g.installer(t, pkg.WithChecksumAsset(`^checksums\.txt$`)).Install("1.0.0", dst))
+
+	release.assets[platformAsset("1.0.0", ".tar.gz")] = tarGz(t, map[string]string{"tool": "tampered"})
+	err := g.installer(t, pkg.WithChecksumAsset(`^checksums\.txt$`)).Install("1.0.0", filepath.Join(t.TempDir(), "tool"))
+	assert.ErrorContains(t, err, "checksum mismatch")
+
+	err = g.installer(t, pkg.WithChecksumAsset(`{{ .AssetName }}\.sha256`)).Install("1.0.0", filepath.Join(t.TempDir(), "tool"))
+	assert.ErrorContains(t, err, "no checksums asset matching")
+}
+
+func TestGitHubReleaseInstaller_SoleChecksumFile(t *testing.T) {
+	binary := []byte("plain binary")
+	name := fmt.Sprintf("tool-linux-%s", runtime.GOARCH)
+	g := newFakeGitHub(t, fakeRelease{
+		tag: "v1.0.0",
+		assets: map[string][]byte{
+			name:             binary,
+			name + ".sha256": []byte(sha256Hex(binary) + "\n"),
+		},
+	})
+	dst := filepath.Join(t.TempDir(), "tool")
+	require.NoError(t, g.installer(t, pkg.WithChecksumAsset(`^{{ .AssetName }}\.sha256$`)).Install("v1.0.0", dst))
+	content, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	assert.Equal(t, binary, content)
+	stat, err := os.Stat(dst)
+	require.NoError(t, err)
+	assert.NotZero(t, stat.Mode()&0100, "a plain binary is made executable")
+}
+
+func TestGitHubReleaseInstaller_GzipAsset(t *testing.T) {
+	var buff bytes.Buffer
+	gw := gzip.NewWriter(&buff)
+	_, _ = gw.Write([]byte("gzipped binary"))
+	require.NoError(t, gw.Close())
+	g := newFakeGitHub(t, fakeRelease{
+		tag:    "v1.0.0",
+		assets: map[string][]byte{fmt.Sprintf("tool-Linux-%s.gz", runtime.GOARCH): buff.Bytes()},
+	})
+	dst := filepath.Join(t.TempDir(), "tool")
+	require.NoError(t, g.installer(t).Install("1.0.0", dst))
+	content, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	assert.Equal(t, "gzipped binary", string(content))
+}
+
+func TestGitHubReleaseInstaller_AssetSelection(t *testing.T) {
+	archive := tarGz(t, map[string]string{"tool": "tool"})
+	aliases := map[string]string{"amd64": "x86_64", "arm64": "aarch64"}
+	alias, ok := aliases[runtime.GOARCH]
+	if !ok {
+		t.Skipf("no arch alias for %s", runtime.GOARCH)
+	}
+	g := newFakeGitHub(t, fakeRelease{
+		tag: "v1.0.0",
+		assets: map[string][]byte{
+			fmt.Sprintf("tool-%s-unknown-linux-gnu.tar.gz", alias):  archive,
+			fmt.Sprintf("tool-%s-unknown-linux-musl.tar.gz", alias): archive,
+			"tool-x86_64-apple-darwin.tar.gz":                       archive,
+		},
+	})
+	err := g.installer(t).Install("1.0.0", filepath.Join(t.TempDir(), "tool"))
+	assert.ErrorContains(t, err, "several assets of owner/tool v1.0.0 match linux/"+runtime.GOARCH)
+
+	require.NoError(t, g.installer(t, pkg.WithAssetPattern(`-linux-musl\.tar\.gz$`)).Install("1.0.0", filepath.Join(t.TempDir(), "tool")))
+
+	err = g.installer(t, pkg.WithAssetPattern(`_{{ .Os }}_{{ .Arch }}\.zip$`)).Install("1.0.0", filepath.Join(t.TempDir(), "tool"))
+	assert.ErrorContains(t, err, "no asset of owner/tool v1.0.0 matches")
+}
+
+func TestGitHubReleaseInstaller_Universal(t *testing.T) {
+	g := newFakeGitHub(t, toolRelease(t, "v1.0.0"))
+	stub := gostub.Stub(&pkg.Os, "darwin")
+	defer stub.Reset()
+	dst := filepath.Join(t.TempDir(), "tool")
+	err := g.installer(t).Install("1.0.0", dst)
+	// The fake darwin_all asset isn't a real archive, picking it is what matters.
+	assert.ErrorContains(t, err, "tool_1.0.0_darwin_all.tar.gz")
+}
+
+func TestGitHubReleaseInstaller_Token(t *testing.T) {
+	g := newFakeGitHub(t, toolRelease(t, "v1.0.0"))
+	t.Setenv("GITHUB_TOKEN", "")
+	_, err := g.installer(t).ListRemote()
+	require.NoError(t, err)
+	assert.Equal(t, "", g.authorizations[0])
+
+	t.Setenv("GITHUB_TOKEN", "gh-secret")
+	require.NoError(t, g.installer(t).Install("1.0.0", filepath.Join(t.TempDir(), "tool")))
+	for _, authorization := range g.authorizations[1:] {
+		assert.Equal(t, "Bearer gh-secret", authorization)
+	}
+}
+
+func TestGitHubReleaseInstaller_RegistryHeadersStayWithDownloadHost(t *testing.T) {
+	g := newFakeGitHub(t, toolRelease(t, "v1.0.0"))
+	t.Setenv("GITHUB_TOKEN", "gh-secret")
+	var downloadAuthorization string
+	download := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloadAuthorization = r.Header.Get("Authorization")
+		http.NotFound(w, r)
+	}))
+	defer download.Close()
+	registryDir := t.TempDir()
+	definition := fmt.Sprintf(`{"binaries":["tool"],"download_url_template":"%s/{{ .Version }}/tool","headers":{"Authorization":"Bearer artifactory"},"github_repo":"owner/tool","github_api_url":"%s"}`, download.URL, g.URL)
+	require.NoError(t, os.WriteFile(filepath.Join(registryDir, "tool.json"), []byte(definition), 0644))
+	registry, err := pkg.LoadRegistry(registryDir)
+	require.NoError(t, err)
+	env, err := registry.Env("tool", t.TempDir(), context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, env.Install("1.0.0"))
+	assert.Equal(t, "Bearer artifactory", downloadAuthorization)
+	require.NotEmpty(t, g.authorizations)
+	for _, authorization := range g.authorizations {
+		assert.Equal(t, "Bearer gh-secret", authorization)
+	}
+}
+
+func TestGitHubReleaseInstaller_RateLimit(t *testing.T) {
+	useOsFs(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+	sut, err := pkg.NewGitHubReleaseInstaller("owner/tool", nil, pkg.WithGitHubApiUrl(server.URL))
+	require.NoError(t, err)
+	_, err = sut.ListRemote()
+	assert.ErrorContains(t, err, "set GITHUB_TOKEN")
+}
+
+func TestNewGitHubReleaseInstaller_Invalid(t *testing.T) {
+	for desc, c := range map[string]struct {
+		repo string
+		opt  pkg.GitHubReleaseOption
+	}{
+		"no_owner":         {repo: "tool"},
+		"too_deep":         {repo: "owner/tool/extra"},
+		"invalid_regexp":   {repo: "owner/tool", opt: pkg.WithAssetPattern(`linux(`)},
+		"invalid_template": {repo: "owner/tool", opt: pkg.WithChecksumAsset(`{{ .Unknown }}`)},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			var opts []pkg.GitHubReleaseOption
+			if c.opt != nil {
+				opts = append(opts, c.opt)
+			}
+			_, err := pkg.NewGitHubReleaseInstaller(c.repo, nil, opts...)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -1,38 +1,78 @@
 package pkg
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
+	"github.com/Masterminds/semver/v3"
 	getter2 "github.com/hashicorp/go-getter/v2"
 )
 
-var _ Installer = &GoBuildInstaller{}
+var _ InfoInstaller = &GoBuildInstaller{}
+var _ RemoteLister = &GoBuildInstaller{}
+
+// defaultMinGoVersion is the first Go release that understands GOTOOLCHAIN,
+// older toolchains cannot switch to the version required by the cloned repo.
+const defaultMinGoVersion = "1.21"
 
 type GoBuildInstaller struct {
-	repoUrl    string
-	subPath    string
-	ctx        context.Context
-	binaryName string
+	repoUrl      string
+	subPath      string
+	ctx          context.Context
+	binaryName   string
+	goBinary     string
+	minGoVersion string
+}
+
+type GoBuildOption func(*GoBuildInstaller)
+
+// WithGoBinary makes the installer build with the given go binary instead of
+// the one found in PATH. The binary is used as is, GOTOOLCHAIN won't be set.
+func WithGoBinary(path string) GoBuildOption {
+	return func(g *GoBuildInstaller) {
+		g.goBinary = path
+	}
+}
+
+// WithMinGoVersion overrides the minimum go version that Available requires.
+func WithMinGoVersion(version string) GoBuildOption {
+	return func(g *GoBuildInstaller) {
+		g.minGoVersion = version
+	}
 }
 
-func NewGoBuildInstaller(repoUrl string, binaryName string, subPath string, ctx context.Context) Installer {
+func NewGoBuildInstaller(repoUrl string, binaryName string, subPath string, ctx context.Context, opts ...GoBuildOption) Installer {
 	if ctx == nil {
 		ctx = context.TODO()
 	}
-	return &GoBuildInstaller{
-		repoUrl:    repoUrl,
-		subPath:    subPath,
-		ctx:        ctx,
-		binaryName: binaryName,
+	g := &GoBuildInstaller{
+		repoUrl:      repoUrl,
+		subPath:      subPath,
+		ctx:          ctx,
+		binaryName:   binaryName,
+		goBinary:     "go",
+		minGoVersion: defaultMinGoVersion,
+	}
+	for _, opt := range opts {
+		opt(g)
 	}
+	return g
 }
 
 func (g *GoBuildInstaller) Install(version string, dstPath string) error {
+	_, err := g.InstallWithInfo(version, dstPath)
+	return err
+}
+
+func (g *GoBuildInstaller) InstallWithInfo(version string, dstPath string) (*InstallInfo, error) {
 	tmpDir := filepath.Join(os.TempDir(), randStr(8))
 	defer func() {
 		_ = os.RemoveAll(tmpDir)
@@ -43,34 +83,179 @@ func (g *GoBuildInstaller) Install(version string, dstPath string) error {
 		src = fmt.Sprintf("%s?ref=%s", g.repoUrl, version)
 	}
 	src = fmt.Sprintf("git::%s", src)
-	fmt.Printf("Go build %s\n", g.repoUrl)
+	infof("Go build %s\n", g.repoUrl)
 	_, err := getter2.Get(g.ctx, tmpDir, src)
 	if err != nil {
-		fmt.Printf("Failed to clone %s: %s\n", g.repoUrl, err.Error())
-		return err
+		warnf("Failed to clone %s: %s\n", g.repoUrl, err.Error())
+		return nil, err
+	}
+	toolchain, err := g.toolchain(tmpDir)
+	if err != nil {
+		warnf("Failed to read go.mod at %s: %s\n", tmpDir, err.Error())
+		return nil, err
+	}
+	if toolchain != "" {
+		debugf("GOTOOLCHAIN=%s\n", toolchain)
 	}
-	fmt.Printf("go mod download at %s\n", tmpDir)
-	err = executeCommand(tmpDir, "go", "mod", "download")
+	debugf("go mod download at %s\n", tmpDir)
+	err = g.goCommand(tmpDir, toolchain, "mod", "download").Run()
 	if err != nil {
-		fmt.Printf("Failed to download go mod at %s: %s\n", tmpDir, err.Error())
-		return err
+		warnf("Failed to download go mod at %s: %s\n", tmpDir, err.Error())
+		return nil, err
 	}
 	args := []string{"build", "-o", dstPath}
 	if g.subPath != "" {
 		args = append(args, g.subPath)
 	}
-	fmt.Printf("go build -o %s\n", args[2])
-	return executeCommand(tmpDir, "go", args...)
+	infof("go build -o %s\n", args[2])
+	if err = g.goCommand(tmpDir, toolchain, args...).Run(); err != nil {
+		return nil, err
+	}
+	info := &InstallInfo{
+		Installer: "go-build",
+		Source:    g.repoUrl,
+	}
+	// Both are best effort, a missing commit or toolchain shouldn't fail a successful build.
+	if commit, err := commandOutput(tmpDir, "git", "rev-parse", "HEAD"); err == nil {
+		info.Commit = commit
+	}
+	if goVer, err := output(g.goCommand(tmpDir, toolchain, "env", "GOVERSION")); err == nil {
+		info.GoToolchain = goVer
+	}
+	return info, nil
+}
+
+// ListRemote lists the tags of the repository.
+func (g *GoBuildInstaller) ListRemote() ([]string, error) {
+	out, err := exec.CommandContext(g.ctx, "git", "ls-remote", "--tags", "--refs", g.repoUrl).Output()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list tags of %s: %w", g.repoUrl, err)
+	}
+	var tags []string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if tag, ok := strings.CutPrefix(fields[1], "refs/tags/"); ok {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
 }
 
 func (g *GoBuildInstaller) Available() bool {
-	return exec.Command("go", "version").Run() == nil
+	cmd := exec.Command(g.goBinary, "env", "GOVERSION")
+	cmd.Env = append(os.Environ(), "GOTOOLCHAIN=local")
+	output, err := cmd.Output()
+	if err != nil {
+		return false
+	}
+	current, err := goVersion(strings.TrimSpace(string(output)))
+	if err != nil {
+		// devel builds don't carry a release version, trust them.
+		return strings.Contains(string(output), "devel")
+	}
+	minimum, err := goVersion(g.minGoVersion)
+	if err != nil {
+		return false
+	}
+	return !current.LessThan(minimum)
+}
+
+// RequiredToolchain returns the GOTOOLCHAIN value that satisfies the given go.mod content,
+// the toolchain directive wins over the go directive. An empty string means the local
+// toolchain should be used, either because go.mod doesn't ask for a version or because the
+// required version predates toolchain switching.
+func RequiredToolchain(goMod []byte) string {
+	var goDirective, toolchainDirective string
+	scanner := bufio.NewScanner(bytes.NewReader(goMod))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		switch fields[0] {
+		case "go":
+			goDirective = fields[1]
+		case "toolchain":
+			toolchainDirective = fields[1]
+		}
+	}
+	if toolchainDirective != "" && toolchainDirective != "default" {
+		return toolchainDirective
+	}
+	if goDirective == "" {
+		return ""
+	}
+	v, err := goVersion(goDirective)
+	if err != nil {
+		return ""
+	}
+	minimum, _ := goVersion(defaultMinGoVersion)
+	if v.LessThan(minimum) {
+		return ""
+	}
+	// Since go 1.21 the language version "1.N" is released as toolchain "go1.N.0".
+	if strings.Count(goDirective, ".") == 1 && strings.IndexFunc(goDirective, isNotVersionRune) < 0 {
+		return fmt.Sprintf("go%s.0", goDirective)
+	}
+	return fmt.Sprintf("go%s", goDirective)
+}
+
+func (g *GoBuildInstaller) toolchain(repoDir string) (string, error) {
+	if g.goBinary != "go" {
+		return "local", nil
+	}
+	goMod, err := os.ReadFile(filepath.Join(repoDir, "go.mod"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", err
+	}
+	return RequiredToolchain(goMod), nil
 }
 
-func executeCommand(wd string, name string, args ...string) error {
+func (g *GoBuildInstaller) goCommand(wd string, toolchain string, args ...string) *exec.Cmd {
+	cmd := exec.Command(g.goBinary, args...)
+	cmd.Dir = wd
+	if toolchain != "" {
+		cmd.Env = append(os.Environ(), fmt.Sprintf("GOTOOLCHAIN=%s", toolchain))
+	}
+	return cmd
+}
+
+func commandOutput(wd string, name string, args ...string) (string, error) {
 	cmd := exec.Command(name, args...)
 	cmd.Dir = wd
-	return cmd.Run()
+	return output(cmd)
+}
+
+func output(cmd *exec.Cmd) (string, error) {
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// goVersion parses versions like "go1.22.3", "1.21" or "1.22rc1", pre-release suffixes are dropped.
+func goVersion(v string) (*semver.Version, error) {
+	v = strings.TrimPrefix(v, "go")
+	if i := strings.IndexFunc(v, isNotVersionRune); i >= 0 {
+		v = v[:i]
+	}
+	return semver.NewVersion(v)
+}
+
+func isNotVersionRune(r rune) bool {
+	return (r < '0' || r > '9') && r != '.'
 }
 
 func randStr(n int) string {
@@ -2,8 +2,10 @@ package pkg_test
 
 import (
 	"context"
+	"fmt"
 	"github.com/lonegunmanb/genv/pkg"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"runtime"
 	"testing"
@@ -113,3 +115,124 @@ func fileExist(path string) (found bool, err error) {
 
 	return
 }
+
+func (g *goBuildInstallerSuite) TestRequiredToolchain() {
+	cases := []struct {
+		desc     string
+		goMod    string
+		expected string
+	}{
+		{
+			desc:     "toolchain_directive",
+			goMod:    "module example.com/foo\n\ngo 1.21\n\ntoolchain go1.21.5\n",
+			expected: "go1.21.5",
+		},
+		{
+			desc:     "default_toolchain_directive",
+			goMod:    "module example.com/foo\n\ngo 1.22.1\n\ntoolchain default\n",
+			expected: "go1.22.1",
+		},
+		{
+			desc:     "go_directive_with_patch",
+			goMod:    "module example.com/foo\n\ngo 1.22.3\n",
+			expected: "go1.22.3",
+		},
+		{
+			desc:     "go_directive_without_patch",
+			goMod:    "module example.com/foo\n\ngo 1.21 // comment\n",
+			expected: "go1.21.0",
+		},
+		{
+			desc:     "release_candidate",
+			goMod:    "module example.com/foo\n\ngo 1.22rc1\n",
+			expected: "go1.22rc1",
+		},
+		{
+			desc:     "predates_toolchain_switching",
+			goMod:    "module example.com/foo\n\ngo 1.16\n",
+			expected: "",
+		},
+		{
+			desc:     "no_go_directive",
+			goMod:    "module example.com/foo\n",
+			expected: "",
+		},
+	}
+	for _, c := range cases {
+		cc := c
+		g.Run(cc.desc, func() {
+			g.Equal(cc.expected, pkg.RequiredToolchain([]byte(cc.goMod)))
+		})
+	}
+}
+
+func (g *goBuildInstallerSuite) TestAvailable() {
+	if runtime.GOOS == "windows" {
+		g.T().Skip("fake go binary is a shell script")
+	}
+	cases := []struct {
+		desc       string
+		goVersion  string
+		minVersion string
+		expected   bool
+	}{
+		{
+			desc:       "newer_than_minimum",
+			goVersion:  "go1.22.3",
+			minVersion: "1.21",
+			expected:   true,
+		},
+		{
+			desc:       "equal_to_minimum",
+			goVersion:  "go1.21.0",
+			minVersion: "1.21",
+			expected:   true,
+		},
+		{
+			desc:       "older_than_minimum",
+			goVersion:  "go1.20.14",
+			minVersion: "1.21",
+			expected:   false,
+		},
+		{
+			desc:       "devel",
+			goVersion:  "devel go1.23-e8ee1dc4f9 Fri Jun 14 16:31:38 2024 +0000",
+			minVersion: "1.21",
+			expected:   true,
+		},
+	}
+	for _, c := range cases {
+		cc := c
+		g.Run(cc.desc, func() {
+			fakeGo := filepath.Join(g.outputFolder, "go")
+			script := fmt.Sprintf("#!/bin/sh\necho '%s'\n", cc.goVersion)
+			g.Require().NoError(os.WriteFile(fakeGo, []byte(script), 0755))
+			installer := pkg.NewGoBuildInstaller("https://github.com/hashicorp/http-echo.git", "http-echo", "", context.Background(), pkg.WithGoBinary(fakeGo), pkg.WithMinGoVersion(cc.minVersion))
+			g.Equal(cc.expected, installer.Available())
+		})
+	}
+}
+
+func (g *goBuildInstallerSuite) TestAvailable_MissingGoBinary() {
+	installer := pkg.NewGoBuildInstaller("https://github.com/hashicorp/http-echo.git", "http-echo", "", context.Background(), pkg.WithGoBinary(filepath.Join(g.outputFolder, "not-exist")))
+	g.False(installer.Available())
+}
+
+func (g *goBuildInstallerSuite) TestListRemote() {
+	repo := filepath.Join(g.outputFolder, "repo")
+	g.Require().NoError(os.MkdirAll(repo, 0755))
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"-c", "user.name=genv", "-c", "user.email=genv@example.com", "commit", "-q", "--allow-empty", "-m", "init"},
+		{"tag", "v1.0.0"},
+		{"tag", "v1.1.0"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		g.Require().NoError(cmd.Run())
+	}
+	installer := pkg.NewGoBuildInstaller(repo, "foo", "", context.Background())
+	versions, err := installer.(pkg.RemoteLister).ListRemote()
+	g.NoError(err)
+	g.ElementsMatch([]string{"v1.0.0", "v1.1.0"}, versions)
+}
@@ -0,0 +1,370 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	getter2 "github.com/hashicorp/go-getter/v2"
+	"github.com/spf13/afero"
+)
+
+// DownloadOption configures the HTTP client of a DownloadInstaller.
+type DownloadOption func(*httpOptions)
+
+type httpOptions struct {
+	caBundle           string
+	clientCert         string
+	clientKey          string
+	insecureSkipVerify bool
+	minTLSVersion      uint16
+	proxy              string
+	netrcFile          string
+	headerTemplates    map[string]string
+	connectTimeout     time.Duration
+	readTimeout        time.Duration
+	timeout            time.Duration
+	retries            int
+	minBackoff         time.Duration
+	maxBackoff         time.Duration
+}
+
+// Defaults of the download timeouts and retries, see the matching options.
+const (
+	DefaultConnectTimeout = 30 * time.Second
+	DefaultReadTimeout    = time.Minute
+	DefaultRetries        = 3
+)
+
+func defaultHttpOptions() httpOptions {
+	return httpOptions{
+		connectTimeout: DefaultConnectTimeout,
+		readTimeout:    DefaultReadTimeout,
+		retries:        DefaultRetries,
+		minBackoff:     time.Second,
+		maxBackoff:     30 * time.Second,
+	}
+}
+
+// WithCABundle trusts the PEM encoded certificates in path, on top of the system roots, for
+// servers signed by an internal CA.
+func WithCABundle(path string) DownloadOption {
+	return func(o *httpOptions) {
+		o.caBundle = path
+	}
+}
+
+// WithClientCertificate authenticates with a client certificate, both files are PEM encoded.
+func WithClientCertificate(certFile, keyFile string) DownloadOption {
+	return func(o *httpOptions) {
+		o.clientCert = certFile
+		o.clientKey = keyFile
+	}
+}
+
+// WithInsecureSkipVerify disables the verification of server certificates, only use it for tests.
+func WithInsecureSkipVerify() DownloadOption {
+	return func(o *httpOptions) {
+		o.insecureSkipVerify = true
+	}
+}
+
+// WithMinTLSVersion refuses servers that don't speak at least the given TLS version, like
+// tls.VersionTLS13.
+func WithMinTLSVersion(version uint16) DownloadOption {
+	return func(o *httpOptions) {
+		o.minTLSVersion = version
+	}
+}
+
+// WithProxy sends requests through the given proxy url instead of the one in HTTPS_PROXY.
+func WithProxy(proxyUrl string) DownloadOption {
+	return func(o *httpOptions) {
+		o.proxy = proxyUrl
+	}
+}
+
+// WithNetrc reads basic auth credentials for the download host from the netrc file at path
+// instead of $NETRC or ~/.netrc.
+func WithNetrc(path string) DownloadOption {
+	return func(o *httpOptions) {
+		o.netrcFile = path
+	}
+}
+
+// WithHeader adds a header to every request, the value is a template that can read env vars,
+// like `Bearer {{ env "ARTIFACTORY_TOKEN" }}`, so secrets don't end up in the url template.
+func WithHeader(name, valueTemplate string) DownloadOption {
+	return func(o *httpOptions) {
+		if o.headerTemplates == nil {
+			o.headerTemplates = make(map[string]string)
+		}
+		o.headerTemplates[name] = valueTemplate
+	}
+}
+
+// WithConnectTimeout bounds the time it takes to connect to the server, TLS handshake included.
+// Zero means no limit.
+func WithConnectTimeout(timeout time.Duration) DownloadOption {
+	return func(o *httpOptions) {
+		o.connectTimeout = timeout
+	}
+}
+
+// WithReadTimeout bounds the time the server may stay silent, waiting for the response headers
+// or for the next bytes of the body. A stalled body is resumed like a broken one. Zero means no
+// limit.
+func WithReadTimeout(timeout time.Duration) DownloadOption {
+	return func(o *httpOptions) {
+		o.readTimeout = timeout
+	}
+}
+
+// WithTimeout bounds a whole download, retries included. Zero, the default, means no limit.
+func WithTimeout(timeout time.Duration) DownloadOption {
+	return func(o *httpOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithRetries sets how many times a request is retried after a server error or a broken
+// connection, a broken download is resumed with a Range request.
+func WithRetries(retries int) DownloadOption {
+	return func(o *httpOptions) {
+		o.retries = retries
+	}
+}
+
+// WithRetryBackoff sets the wait before the first retry, it doubles with every retry up to max
+// and is jittered so clients don't retry in lockstep.
+func WithRetryBackoff(min, max time.Duration) DownloadOption {
+	return func(o *httpOptions) {
+		o.minBackoff = min
+		o.maxBackoff = max
+	}
+}
+
+var headerFuncs = template.FuncMap{
+	"env": os.Getenv,
+}
+
+// renderEnvTemplate renders a template that can read env vars, like a header value.
+func renderEnvTemplate(name, tpl string) (string, error) {
+	t, err := template.New(name).Funcs(headerFuncs).Parse(tpl)
+	if err != nil {
+		return "", err
+	}
+	var buff bytes.Buffer
+	if err = t.Execute(&buff, nil); err != nil {
+		return "", err
+	}
+	return buff.String(), nil
+}
+
+func (o *httpOptions) validate() error {
+	if o.connectTimeout < 0 || o.readTimeout < 0 || o.timeout < 0 {
+		return fmt.Errorf("timeouts can't be negative")
+	}
+	if o.retries < 0 {
+		return fmt.Errorf("retries can't be negative")
+	}
+	if o.minBackoff <= 0 || o.maxBackoff < o.minBackoff {
+		return fmt.Errorf("invalid retry backoff from %s to %s", o.minBackoff, o.maxBackoff)
+	}
+	for name, tpl := range o.headerTemplates {
+		if _, err := template.New(name).Funcs(headerFuncs).Parse(tpl); err != nil {
+			return fmt.Errorf("invalid template for header %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// client builds the http.Client, its transport retries failed requests.
+func (o *httpOptions) client() (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   o.connectTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = o.connectTimeout
+	transport.ResponseHeaderTimeout = o.readTimeout
+	if o.caBundle != "" || o.clientCert != "" || o.insecureSkipVerify || o.minTLSVersion != 0 {
+		tlsConfig, err := o.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	if o.proxy != "" {
+		proxyUrl, err := url.Parse(o.proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %s: %w", o.proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyUrl)
+	}
+	return &http.Client{Transport: &retryTransport{
+		base:        transport,
+		retries:     o.retries,
+		minBackoff:  o.minBackoff,
+		maxBackoff:  o.maxBackoff,
+		readTimeout: o.readTimeout,
+	}}, nil
+}
+
+func (o *httpOptions) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// #nosec G402 -- opt-in, documented as test only.
+		InsecureSkipVerify: o.insecureSkipVerify,
+	}
+	if o.minTLSVersion != 0 {
+		tlsConfig.MinVersion = o.minTLSVersion
+	}
+	if o.caBundle != "" {
+		pem, err := afero.ReadFile(Fs, o.caBundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in CA bundle %s", o.caBundle)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if o.clientCert != "" {
+		certPEM, err := afero.ReadFile(Fs, o.clientCert)
+		if err != nil {
+			return nil, err
+		}
+		keyPEM, err := afero.ReadFile(Fs, o.clientKey)
+		if err != nil {
+			return nil, err
+		}
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// header renders the header templates and adds the netrc credentials of the url's host.
+func (o *httpOptions) header(rawUrl string) (http.Header, error) {
+	header := make(http.Header)
+	for name, tpl := range o.headerTemplates {
+		value, err := renderEnvTemplate(name, tpl)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render header %s: %w", name, err)
+		}
+		header.Set(name, value)
+	}
+	if o.netrcFile != "" && header.Get("Authorization") == "" {
+		u, err := url.Parse(rawUrl)
+		if err != nil {
+			return nil, err
+		}
+		login, password, err := netrcCredentials(o.netrcFile, u.Hostname())
+		if err != nil {
+			return nil, err
+		}
+		if login != "" {
+			header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(login+":"+password)))
+		}
+	}
+	return header, nil
+}
+
+// getterClient returns a go-getter client whose http getter uses httpClient and these options.
+// Timeouts are enforced by httpClient, so the getter's own are disabled, and ~/.netrc is read
+// unless another netrc file is given.
+func (o *httpOptions) getterClient(httpClient *http.Client, rawUrl string) (*getter2.Client, error) {
+	header, err := o.header(rawUrl)
+	if err != nil {
+		return nil, err
+	}
+	httpGetter := &getter2.HttpGetter{
+		Netrc:                 o.netrcFile == "",
+		Client:                httpClient,
+		Header:                header,
+		XTerraformGetDisabled: true,
+		HeadFirstTimeout:      time.Duration(0),
+		ReadTimeout:           time.Duration(0),
+	}
+	getters := make([]getter2.Getter, 0, len(getter2.Getters))
+	for _, g := range getter2.Getters {
+		if _, ok := g.(*getter2.HttpGetter); ok {
+			g = httpGetter
+		}
+		getters = append(getters, g)
+	}
+	return &getter2.Client{Getters: getters}, nil
+}
+
+// netrcCredentials returns the login and password of host, or of the default entry, from the
+// netrc file at path.
+func netrcCredentials(path, host string) (string, string, error) {
+	content, err := afero.ReadFile(Fs, path)
+	if err != nil {
+		return "", "", err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Split(bufio.ScanWords)
+	var tokens []string
+	for scanner.Scan() {
+		tokens = append(tokens, scanner.Text())
+	}
+	var login, password, defaultLogin, defaultPassword string
+	matched, isDefault := false, false
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "machine":
+			if matched {
+				return login, password, nil
+			}
+			isDefault = false
+			if i+1 < len(tokens) {
+				i++
+				matched = strings.EqualFold(tokens[i], host)
+			}
+		case "default":
+			if matched {
+				return login, password, nil
+			}
+			isDefault = true
+		case "login", "password":
+			if i+1 >= len(tokens) {
+				continue
+			}
+			key, value := tokens[i], tokens[i+1]
+			i++
+			switch {
+			case matched && key == "login":
+				login = value
+			case matched:
+				password = value
+			case isDefault && key == "login":
+				defaultLogin = value
+			case isDefault:
+				defaultPassword = value
+			}
+		}
+	}
+	if matched {
+		return login, password, nil
+	}
+	return defaultLogin, defaultPassword, nil
+}
@@ -0,0 +1,116 @@
+package pkg_test
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lonegunmanb/genv/pkg"
+	"github.com/prashantv/gostub"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useOsFs lets the installer read the CA bundles and netrc files written to t.TempDir().
+func useOsFs(t *testing.T) {
+	stub := gostub.Stub(&pkg.Fs, afero.NewOsFs())
+	t.Cleanup(stub.Reset)
+}
+
+// newTLSServer serves a fake terraform binary for requests that pass check.
+func newTLSServer(t *testing.T, check func(r *http.Request) bool) *httptest.Server {
+	useOsFs(t)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !check(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte("fake terraform"))
+	}))
+	server.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func caBundle(t *testing.T, server *httptest.Server) string {
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	content := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(path, content, 0600))
+	return path
+}
+
+func download(t *testing.T, server *httptest.Server, opts ...pkg.DownloadOption) error {
+	installer, err := pkg.NewDownloadInstaller(server.URL+"/{{ .Version }}/terraform", nil, opts...)
+	require.NoError(t, err)
+	dst := filepath.Join(t.TempDir(), "terraform")
+	if err = installer.Install("1.0.0", dst); err != nil {
+		return err
+	}
+	content, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	assert.Equal(t, "fake terraform", string(content))
+	return nil
+}
+
+func TestDownloadInstaller_CABundle(t *testing.T) {
+	server := newTLSServer(t, func(r *http.Request) bool { return true })
+	assert.ErrorContains(t, download(t, server), "certificate")
+	assert.NoError(t, download(t, server, pkg.WithCABundle(caBundle(t, server))))
+	assert.NoError(t, download(t, server, pkg.WithInsecureSkipVerify()))
+}
+
+func TestDownloadInstaller_InvalidCABundle(t *testing.T) {
+	useOsFs(t)
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(path, []byte("not a certificate"), 0600))
+	_, err := pkg.NewDownloadInstaller("https://example.com/{{ .Version }}", nil, pkg.WithCABundle(path))
+	assert.ErrorContains(t, err, "no certificate found")
+	_, err = pkg.NewDownloadInstaller("https://example.com/{{ .Version }}", nil, pkg.WithCABundle(filepath.Join(t.TempDir(), "missing.pem")))
+	assert.ErrorContains(t, err, "failed to read CA bundle")
+}
+
+func TestDownloadInstaller_MinTLSVersion(t *testing.T) {
+	server := newTLSServer(t, func(r *http.Request) bool { return true })
+	err := download(t, server, pkg.WithCABundle(caBundle(t, server)), pkg.WithMinTLSVersion(tls.VersionTLS13))
+	assert.ErrorContains(t, err, "protocol version")
+}
+
+func TestDownloadInstaller_HeaderTemplate(t *testing.T) {
+	t.Setenv("GENV_TEST_TOKEN", "s3cr3t")
+	server := newTLSServer(t, func(r *http.Request) bool {
+		return r.Header.Get("Authorization") == "Bearer s3cr3t" && r.Header.Get("X-Team") == "platform"
+	})
+	ca := caBundle(t, server)
+	assert.Error(t, download(t, server, pkg.WithCABundle(ca)))
+	assert.NoError(t, download(t, server,
+		pkg.WithCABundle(ca),
+		pkg.WithHeader("Authorization", `Bearer {{ env "GENV_TEST_TOKEN" }}`),
+		pkg.WithHeader("X-Team", "platform")))
+
+	_, err := pkg.NewDownloadInstaller(server.URL+"/{{ .Version }}", nil, pkg.WithHeader("Authorization", "{{ env"))
+	assert.ErrorContains(t, err, "invalid template for header Authorization")
+}
+
+func TestDownloadInstaller_Netrc(t *testing.T) {
+	server := newTLSServer(t, func(r *http.Request) bool {
+		user, password, ok := r.BasicAuth()
+		return ok && user == "deployer" && password == "pa55"
+	})
+	ca := caBundle(t, server)
+	netrc := filepath.Join(t.TempDir(), "netrc")
+	content := "machine other.example.com login someone password else\n" +
+		"machine 127.0.0.1\n  login deployer\n  password pa55\n" +
+		"default login anonymous password none\n"
+	require.NoError(t, os.WriteFile(netrc, []byte(content), 0600))
+	assert.NoError(t, download(t, server, pkg.WithCABundle(ca), pkg.WithNetrc(netrc)))
+
+	defaultOnly := filepath.Join(t.TempDir(), "netrc")
+	require.NoError(t, os.WriteFile(defaultOnly, []byte("default login deployer password pa55\n"), 0600))
+	assert.NoError(t, download(t, server, pkg.WithCABundle(ca), pkg.WithNetrc(defaultOnly)))
+}
@@ -0,0 +1,257 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// errReadTimeout is returned when a response body doesn't send anything for the read timeout.
+var errReadTimeout = errors.New("timed out reading the response body")
+
+// retryTransport retries GET and HEAD requests that fail with a server error or a broken
+// connection, waiting a jittered exponential backoff between attempts. A GET response body that
+// breaks off or stalls is resumed where it stopped with a Range request.
+type retryTransport struct {
+	base        http.RoundTripper
+	retries     int
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	readTimeout time.Duration
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return t.base.RoundTrip(req)
+	}
+	body := &resumingBody{t: t, req: req, start: rangeStart(req)}
+	resp, err := body.open(0)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = body
+	return resp, nil
+}
+
+// resumingBody reads a response body and, when the connection breaks or stalls, requests the rest
+// of the content with a Range request. Servers that ignore the Range header send everything again
+// and the bytes already read are skipped.
+type resumingBody struct {
+	t   *retryTransport
+	req *http.Request
+	// start is the offset the original request asked for, offset counts the bytes read since.
+	start     int64
+	offset    int64
+	validator string
+	attempt   int
+	body      io.ReadCloser
+	cancel    context.CancelFunc
+	timedOut  atomic.Bool
+	err       error
+}
+
+// open sends the request, asking for the content from offset on, until it gets a response that
+// isn't a server error or the retries run out. The last server error response is returned as is.
+func (b *resumingBody) open(offset int64) (*http.Response, error) {
+	for {
+		ctx, cancel := context.WithCancel(b.req.Context())
+		req := b.req.Clone(ctx)
+		if offset > 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", b.start+offset))
+			if b.validator != "" {
+				req.Header.Set("If-Range", b.validator)
+			}
+		}
+		resp, err := b.t.base.RoundTrip(req)
+		if err == nil && (!retryableStatus(resp.StatusCode) || b.attempt >= b.t.retries) {
+			if b.validator == "" {
+				b.validator = rangeValidator(resp)
+			}
+			b.body, b.cancel = resp.Body, cancel
+			b.timedOut.Store(false)
+			return resp, nil
+		}
+		if err == nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+			err = fmt.Errorf("%s %s: %s", req.Method, req.URL.Redacted(), resp.Status)
+		} else if !retryable(err) || b.attempt >= b.t.retries {
+			cancel()
+			return nil, err
+		}
+		cancel()
+		if err = b.backoff(err); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// backoff waits before the next attempt, a random time between half and all of a window that
+// doubles with every attempt.
+func (b *resumingBody) backoff(cause error) error {
+	window := b.t.minBackoff << b.attempt
+	if window <= 0 || window > b.t.maxBackoff {
+		window = b.t.maxBackoff
+	}
+	wait := window/2 + time.Duration(rand.Int64N(int64(window/2)+1))
+	b.attempt++
+	warnf("Retrying %s in %s (%d/%d): %s\n", b.req.URL.Redacted(), wait.Round(time.Millisecond), b.attempt, b.t.retries, cause.Error())
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-b.req.Context().Done():
+		return b.req.Context().Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (b *resumingBody) Read(p []byte) (int, error) {
+	for {
+		if b.err == nil {
+			n, err := b.read(p)
+			b.offset += int64(n)
+			if err == nil || errors.Is(err, io.EOF) {
+				return n, err
+			}
+			b.err = err
+			if n > 0 {
+				return n, nil
+			}
+		}
+		if err := b.resume(); err != nil {
+			return 0, err
+		}
+	}
+}
+
+func (b *resumingBody) read(p []byte) (int, error) {
+	if b.t.readTimeout <= 0 {
+		return b.body.Read(p)
+	}
+	cancel := b.cancel
+	timer := time.AfterFunc(b.t.readTimeout, func() {
+		b.timedOut.Store(true)
+		cancel()
+	})
+	n, err := b.body.Read(p)
+	timer.Stop()
+	if err != nil && b.timedOut.Load() && b.req.Context().Err() == nil {
+		err = errReadTimeout
+	}
+	return n, err
+}
+
+func (b *resumingBody) resume() error {
+	cause := b.err
+	if !retryable(cause) || b.attempt >= b.t.retries {
+		return cause
+	}
+	b.close()
+	if err := b.backoff(cause); err != nil {
+		return err
+	}
+	resp, err := b.open(b.offset)
+	if err != nil {
+		return err
+	}
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && contentRangeStart(resp) == b.start+b.offset:
+	case resp.StatusCode == http.StatusOK:
+		if validator := rangeValidator(resp); b.validator != "" && validator != b.validator {
+			b.close()
+			return fmt.Errorf("%s changed while it was downloaded", b.req.URL.Redacted())
+		}
+		if _, err = io.CopyN(io.Discard, resp.Body, b.start+b.offset); err != nil {
+			b.err = err
+			return nil
+		}
+	default:
+		b.close()
+		return fmt.Errorf("failed to resume %s at byte %d: %s", b.req.URL.Redacted(), b.start+b.offset, resp.Status)
+	}
+	b.err = nil
+	return nil
+}
+
+func (b *resumingBody) close() {
+	if b.body != nil {
+		_ = b.body.Close()
+	}
+	if b.cancel != nil {
+		b.cancel()
+	}
+}
+
+func (b *resumingBody) Close() error {
+	b.close()
+	return nil
+}
+
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+// retryable tells broken or stalled connections, which are worth another attempt, from errors
+// like an untrusted certificate that would fail again. A refused connection isn't retried so the
+// next installer of a chain gets its turn right away.
+func retryable(err error) bool {
+	if errors.Is(err, errReadTimeout) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// rangeValidator returns the strong ETag or the Last-Modified date of the response, sent as
+// If-Range so a resumed download doesn't mix two versions of a file.
+func rangeValidator(resp *http.Response) string {
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return resp.Header.Get("Last-Modified")
+}
+
+// rangeStart returns the first byte asked for by a "bytes=N-" Range header, 0 without one.
+func rangeStart(req *http.Request) int64 {
+	spec, ok := strings.CutPrefix(req.Header.Get("Range"), "bytes=")
+	if !ok {
+		return 0
+	}
+	first, _, _ := strings.Cut(spec, "-")
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return start
+}
+
+// contentRangeStart returns the first byte of a "bytes N-M/size" Content-Range header, -1 when
+// it's missing or invalid.
+func contentRangeStart(resp *http.Response) int64 {
+	spec, ok := strings.CutPrefix(resp.Header.Get("Content-Range"), "bytes ")
+	if !ok {
+		return -1
+	}
+	first, _, _ := strings.Cut(spec, "-")
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return start
+}
@@ -0,0 +1,189 @@
+package pkg_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lonegunmanb/genv/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyServer serves content, failing the GET requests for which fail returns true. The
+// attempt numbers start at 1, HEAD requests aren't counted.
+type flakyServer struct {
+	*httptest.Server
+	content []byte
+	fail    func(attempt int, w http.ResponseWriter, r *http.Request) bool
+
+	mu       sync.Mutex
+	attempts int
+	ranges   []string
+}
+
+func newFlakyServer(t *testing.T, fail func(attempt int, w http.ResponseWriter, r *http.Request) bool) *flakyServer {
+	useOsFs(t)
+	s := &flakyServer{
+		content: bytes.Repeat([]byte("0123456789"), 10000),
+		fail:    fail,
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			s.mu.Lock()
+			s.attempts++
+			attempt := s.attempts
+			s.ranges = append(s.ranges, r.Header.Get("Range"))
+			s.mu.Unlock()
+			if s.fail(attempt, w, r) {
+				return
+			}
+		}
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "terraform", time.Time{}, bytes.NewReader(s.content))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// breakOff sends the headers and half the content, then drops the connection.
+func (s *flakyServer) breakOff(w http.ResponseWriter) {
+	w.Header().Set("Content-Length", strconv.Itoa(len(s.content)))
+	w.Header().Set("Accept-Ranges", "bytes")
+	w.Header().Set("ETag", `"v1"`)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(s.content[:len(s.content)/2])
+	w.(http.Flusher).Flush()
+	panic(http.ErrAbortHandler)
+}
+
+func (s *flakyServer) download(t *testing.T, opts ...pkg.DownloadOption) ([]byte, error) {
+	opts = append([]pkg.DownloadOption{pkg.WithRetryBackoff(time.Millisecond, 10*time.Millisecond)}, opts...)
+	installer, err := pkg.NewDownloadInstaller(s.URL+"/{{ .Version }}/terraform", nil, opts...)
+	require.NoError(t, err)
+	dst := filepath.Join(t.TempDir(), "terraform")
+	if err = installer.Install("1.0.0", dst); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(dst)
+}
+
+func TestDownloadInstaller_RetryServerErrors(t *testing.T) {
+	server := newFlakyServer(t, func(attempt int, w http.ResponseWriter, r *http.Request) bool {
+		if attempt <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return true
+		}
+		return false
+	})
+	content, err := server.download(t)
+	require.NoError(t, err)
+	assert.Equal(t, server.content, content)
+	assert.Equal(t, 3, server.attempts)
+}
+
+func TestDownloadInstaller_GiveUpAfterRetries(t *testing.T) {
+	server := newFlakyServer(t, func(attempt int, w http.ResponseWriter, r *http.Request) bool {
+		w.WriteHeader(http.StatusInternalServerError)
+		return true
+	})
+	_, err := server.download(t, pkg.WithRetries(2))
+	assert.ErrorContains(t, err, "500")
+	assert.Equal(t, 3, server.attempts)
+}
+
+func TestDownloadInstaller_NoRetryOnClientErrors(t *testing.T) {
+	server := newFlakyServer(t, func(attempt int, w http.ResponseWriter, r *http.Request) bool {
+		w.WriteHeader(http.StatusNotFound)
+		return true
+	})
+	_, err := server.download(t)
+	assert.ErrorContains(t, err, "404")
+	assert.Equal(t, 1, server.attempts)
+}
+
+func TestDownloadInstaller_ResumeBrokenDownload(t *testing.T) {
+	var server *flakyServer
+	server = newFlakyServer(t, func(attempt int, w http.ResponseWriter, r *http.Request) bool {
+		if attempt == 1 {
+			server.breakOff(w)
+		}
+		return false
+	})
+	content, err := server.download(t)
+	require.NoError(t, err)
+	assert.Equal(t, server.content, content)
+	assert.Equal(t, []string{"", "bytes=" + strconv.Itoa(len(server.content)/2) + "-"}, server.ranges)
+}
+
+func TestDownloadInstaller_ResumeWithoutRangeSupport(t *testing.T) {
+	var server *flakyServer
+	server = newFlakyServer(t, func(attempt int, w http.ResponseWriter, r *http.Request) bool {
+		if attempt == 1 {
+			server.breakOff(w)
+		}
+		// Ignore the Range header and send everything again.
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write(server.content)
+		return true
+	})
+	content, err := server.download(t)
+	require.NoError(t, err)
+	assert.Equal(t, server.content, content)
+}
+
+func TestDownloadInstaller_ResumeStalledDownload(t *testing.T) {
+	var server *flakyServer
+	server = newFlakyServer(t, func(attempt int, w http.ResponseWriter, r *http.Request) bool {
+		if attempt > 1 {
+			return false
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(server.content)))
+		w.Header().Set("ETag", `"v1"`)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(server.content[:100])
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+		return true
+	})
+	content, err := server.download(t, pkg.WithReadTimeout(100*time.Millisecond))
+	require.NoError(t, err)
+	assert.Equal(t, server.content, content)
+	assert.Equal(t, "bytes=100-", server.ranges[1])
+}
+
+func TestDownloadInstaller_OverallTimeout(t *testing.T) {
+	server := newFlakyServer(t, func(attempt int, w http.ResponseWriter, r *http.Request) bool {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+		return true
+	})
+	start := time.Now()
+	_, err := server.download(t, pkg.WithTimeout(200*time.Millisecond), pkg.WithReadTimeout(0))
+	assert.ErrorContains(t, err, "context deadline exceeded")
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestDownloadInstaller_InvalidRetryOptions(t *testing.T) {
+	for desc, opt := range map[string]pkg.DownloadOption{
+		"negative_timeout": pkg.WithTimeout(-time.Second),
+		"negative_retries": pkg.WithRetries(-1),
+		"inverted_backoff": pkg.WithRetryBackoff(time.Minute, time.Second),
+	} {
+		t.Run(desc, func(t *testing.T) {
+			_, err := pkg.NewDownloadInstaller("https://example.com/{{ .Version }}", nil, opt)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"time"
+)
+
+// InstallInfo records how an installed version got into place, it's stored as <version>/.genv.json.
+type InstallInfo struct {
+	Version     string    `json:"version"`
+	Installer   string    `json:"installer,omitempty"`
+	Source      string    `json:"source,omitempty"`
+	Commit      string    `json:"commit,omitempty"`
+	Sha256      string    `json:"sha256"`
+	InstalledAt time.Time `json:"installed_at"`
+	GoToolchain string    `json:"go_toolchain,omitempty"`
+}
+
+// InfoInstaller is an Installer that can describe where the installed binary came from.
+type InfoInstaller interface {
+	Installer
+	InstallWithInfo(version string, dstPath string) (*InstallInfo, error)
+}
+
+func installWithInfo(i Installer, version string, dstPath string) (*InstallInfo, error) {
+	if ii, ok := i.(InfoInstaller); ok {
+		return ii.InstallWithInfo(version, dstPath)
+	}
+	if err := i.Install(version, dstPath); err != nil {
+		return nil, err
+	}
+	return &InstallInfo{}, nil
+}
+
+func fileSha256(path string) (string, error) {
+	f, err := Fs.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,47 @@
+package pkg
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Output receives the progress messages of installers, commands whose stdout is parsed, like the
+// control plane's path, point it to stderr.
+var Output io.Writer = os.Stdout
+
+// LogLevels lists the accepted log levels from the most to the least verbose.
+var LogLevels = []string{"debug", "info", "warn", "error"}
+
+var logLevel = 1
+
+// SetLogLevel only lets messages at level or above through, info by default.
+func SetLogLevel(level string) error {
+	i := slices.Index(LogLevels, strings.ToLower(level))
+	if i < 0 {
+		return fmt.Errorf("invalid log level %s, expected one of: %s", level, strings.Join(LogLevels, ", "))
+	}
+	logLevel = i
+	return nil
+}
+
+func debugf(format string, args ...any) {
+	logf(0, format, args...)
+}
+
+func infof(format string, args ...any) {
+	logf(1, format, args...)
+}
+
+func warnf(format string, args ...any) {
+	logf(2, format, args...)
+}
+
+func logf(level int, format string, args ...any) {
+	if level < logLevel {
+		return
+	}
+	_, _ = fmt.Fprintf(Output, format, args...)
+}
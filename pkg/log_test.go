@@ -0,0 +1,33 @@
+package pkg_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/lonegunmanb/genv/pkg"
+	"github.com/prashantv/gostub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetLogLevel(t *testing.T) {
+	var out bytes.Buffer
+	stub := gostub.Stub(&pkg.Output, &out)
+	defer stub.Reset()
+	defer func() {
+		_ = pkg.SetLogLevel("info")
+	}()
+	installer, err := pkg.NewDownloadInstaller("http://127.0.0.1:1/{{ .Version }}", nil)
+	assert.NoError(t, err)
+
+	assert.NoError(t, pkg.SetLogLevel("error"))
+	_ = installer.Install("1.0.0", os.TempDir()+"/genv-log-test/terraform")
+	assert.Empty(t, out.String())
+
+	assert.NoError(t, pkg.SetLogLevel("INFO"))
+	_ = installer.Install("1.0.0", os.TempDir()+"/genv-log-test/terraform")
+	assert.Contains(t, out.String(), "Downloading http://127.0.0.1:1/1.0.0")
+	assert.Contains(t, out.String(), "Failed to download")
+
+	assert.Error(t, pkg.SetLogLevel("loud"))
+}
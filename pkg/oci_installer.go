@@ -0,0 +1,653 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"text/template"
+)
+
+var _ InfoInstaller = &OCIInstaller{}
+var _ RemoteLister = &OCIInstaller{}
+
+// OCIInstallerName is recorded as the installer of versions pulled from an OCI registry.
+const OCIInstallerName = "oci"
+
+// The manifest media types an OCIInstaller accepts, docker's too for images pushed by older
+// tools, and the annotation that names a layer's file.
+const (
+	ociIndexMediaType          = "application/vnd.oci.image.index.v1+json"
+	ociManifestMediaType       = "application/vnd.oci.image.manifest.v1+json"
+	dockerManifestListType     = "application/vnd.docker.distribution.manifest.list.v2+json"
+	dockerManifestMediaType    = "application/vnd.docker.distribution.manifest.v2+json"
+	ociTitleAnnotation         = "org.opencontainers.image.title"
+	dockerHubRegistry          = "registry-1.docker.io"
+	maxOCIManifestSize         = 4 << 20
+	ociManifestAcceptMediaType = ociIndexMediaType + ", " + ociManifestMediaType + ", " + dockerManifestListType + ", " + dockerManifestMediaType
+)
+
+var ociDigestPattern = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// OCIInstaller pulls a binary published as an OCI artifact, like one pushed with `oras push`, or
+// as a layer of an image. The reference template renders to a tag, `registry/repo:{{ .Version }}`,
+// or a digest when the version is one. An index is resolved to the manifest of the running
+// platform, then the layer holding the binary is picked, verified against its digest and
+// extracted when it's an archive.
+type OCIInstaller struct {
+	refTemplate  string
+	layerPattern string
+	plainHttp    bool
+	username     string
+	password     string
+	opts         httpOptions
+	httpClient   *http.Client
+	// token is the bearer token the registry handed out, reused until it's refused.
+	token string
+
+	ctx context.Context
+}
+
+type OCIOption func(*OCIInstaller)
+
+// WithLayerPattern picks the layer whose title annotation matches the regular expression. The
+// pattern is a template rendered with .Version, .Os and .Arch.
+func WithLayerPattern(pattern string) OCIOption {
+	return func(o *OCIInstaller) {
+		o.layerPattern = pattern
+	}
+}
+
+// WithPlainHttp talks to the registry over http instead of https, for local registries.
+func WithPlainHttp() OCIOption {
+	return func(o *OCIInstaller) {
+		o.plainHttp = true
+	}
+}
+
+// WithRegistryCredentials authenticates to the registry, or to its token service, with a username
+// and a password or access token. Without them, the entry of the registry host in the netrc file
+// given with WithNetrc is used.
+func WithRegistryCredentials(username, password string) OCIOption {
+	return func(o *OCIInstaller) {
+		o.username = username
+		o.password = password
+	}
+}
+
+// WithOCIDownloadOptions applies the http options of a DownloadInstaller, like a CA bundle or
+// timeouts, to the registry requests. WithTimeout bounds each request, a blob's download included.
+func WithOCIDownloadOptions(opts ...DownloadOption) OCIOption {
+	return func(o *OCIInstaller) {
+		for _, opt := range opts {
+			opt(&o.opts)
+		}
+	}
+}
+
+// NewOCIInstaller creates an installer for the artifacts referenced by refTemplate, like
+// `ghcr.io/org/tool:{{ .Version }}`.
+func NewOCIInstaller(refTemplate string, ctx context.Context, opts ...OCIOption) (*OCIInstaller, error) {
+	if ctx == nil {
+		ctx = context.TODO()
+	}
+	o := &OCIInstaller{
+		refTemplate: refTemplate,
+		opts:        defaultHttpOptions(),
+		ctx:         ctx,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if _, err := o.reference("1.0.0"); err != nil {
+		return nil, err
+	}
+	if o.layerPattern != "" {
+		if _, err := renderPattern(o.layerPattern, gitHubAssetArgument{}); err != nil {
+			return nil, err
+		}
+	}
+	if err := o.opts.validate(); err != nil {
+		return nil, err
+	}
+	httpClient, err := o.opts.client()
+	if err != nil {
+		return nil, err
+	}
+	o.httpClient = httpClient
+	return o, nil
+}
+
+// ociReference is a parsed reference, tag or digest is set.
+type ociReference struct {
+	registry   string
+	repository string
+	tag        string
+	digest     string
+}
+
+func (r ociReference) String() string {
+	if r.digest != "" {
+		return fmt.Sprintf("%s/%s@%s", r.registry, r.repository, r.digest)
+	}
+	return fmt.Sprintf("%s/%s:%s", r.registry, r.repository, r.tag)
+}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Platform    *struct {
+		Os           string `json:"os"`
+		Architecture string `json:"architecture"`
+	} `json:"platform,omitempty"`
+}
+
+// ociManifest holds the fields of both an index, Manifests, and an image manifest, Layers.
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Manifests []ociDescriptor `json:"manifests"`
+	Layers    []ociDescriptor `json:"layers"`
+}
+
+func (o *OCIInstaller) Available() bool {
+	return true
+}
+
+func (o *OCIInstaller) Install(version string, dstPath string) error {
+	_, err := o.InstallWithInfo(version, dstPath)
+	return err
+}
+
+func (o *OCIInstaller) InstallWithInfo(version string, dstPath string) (*InstallInfo, error) {
+	ref, err := o.reference(version)
+	if err != nil {
+		return nil, err
+	}
+	infof("Pulling %s\n", ref)
+	manifest, digest, err := o.manifest(ref)
+	if err != nil {
+		warnf("Failed to pull %s: %s\n", ref, err.Error())
+		return nil, err
+	}
+	layer, err := o.selectLayer(manifest, version, filepath.Base(dstPath))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", ref, err)
+	}
+	tmpDir, err := os.MkdirTemp("", "genv-oci")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = os.RemoveAll(tmpDir)
+	}()
+	layerPath := filepath.Join(tmpDir, layerFileName(layer))
+	if err = o.blob(ref, layer, layerPath); err != nil {
+		warnf("Failed to pull %s: %s\n", ref, err.Error())
+		return nil, err
+	}
+	if err = installAsset(layerPath, dstPath); err != nil {
+		return nil, err
+	}
+	pinned := ref
+	pinned.tag, pinned.digest = "", digest
+	return &InstallInfo{
+		Installer: OCIInstallerName,
+		Source:    pinned.String(),
+	}, nil
+}
+
+// ListRemote lists the tags of the repository.
+func (o *OCIInstaller) ListRemote() ([]string, error) {
+	ref, err := o.reference("latest")
+	if err != nil {
+		return nil, err
+	}
+	var tags []string
+	next := o.url(ref, "tags/list")
+	for next != "" {
+		resp, err := o.get(ref, next, "application/json")
+		if err != nil {
+			return nil, fmt.Errorf("cannot list tags of %s/%s: %w", ref.registry, ref.repository, err)
+		}
+		var list struct {
+			Tags []string `json:"tags"`
+		}
+		err = json.NewDecoder(io.LimitReader(resp.Body, maxOCIManifestSize)).Decode(&list)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid tag list of %s/%s: %w", ref.registry, ref.repository, err)
+		}
+		tags = append(tags, list.Tags...)
+		next = ""
+		if link := nextPage(resp.Header.Get("Link")); link != "" {
+			// The registry usually links to a path on the same host.
+			base, _ := url.Parse(o.url(ref, ""))
+			if u, err := base.Parse(link); err == nil {
+				next = u.String()
+			}
+		}
+	}
+	return tags, nil
+}
+
+// reference renders the reference template and parses it, a version that's a digest is pulled
+// by digest.
+func (o *OCIInstaller) reference(version string) (ociReference, error) {
+	tplt, err := template.New("ref").Parse(o.refTemplate)
+	if err != nil {
+		return ociReference{}, err
+	}
+	var buff bytes.Buffer
+	err = tplt.Execute(&buff, downloadArgument{
+		Version: version,
+		Os:      Os,
+		Arch:    runtime.GOARCH,
+	})
+	if err != nil {
+		return ociReference{}, err
+	}
+	return parseOCIReference(buff.String())
+}
+
+func parseOCIReference(s string) (ociReference, error) {
+	var ref ociReference
+	name, digest, byDigest := strings.Cut(s, "@")
+	if byDigest {
+		ref.digest = digest
+	} else {
+		repoStart := strings.LastIndex(s, "/") + 1
+		if colon := strings.Index(s[repoStart:], ":"); colon >= 0 {
+			name, ref.tag = s[:repoStart+colon], s[repoStart+colon+1:]
+		}
+		// registry/repo:sha256:... is what the template renders to when the version is a digest.
+		if strings.Contains(ref.tag, ":") {
+			ref.tag, ref.digest = "", ref.tag
+		}
+	}
+	registry, repository, ok := strings.Cut(name, "/")
+	if !ok || !strings.ContainsAny(registry, ".:") && registry != "localhost" {
+		// Docker Hub, like docker pull.
+		registry, repository = dockerHubRegistry, name
+		if !strings.Contains(repository, "/") {
+			repository = "library/" + repository
+		}
+	}
+	if registry == "docker.io" {
+		registry = dockerHubRegistry
+	}
+	ref.registry, ref.repository = registry, repository
+	switch {
+	case repository == "" || strings.ToLower(repository) != repository:
+		return ref, fmt.Errorf("invalid OCI reference %s, the repository must be lower case", s)
+	case ref.digest != "" && !ociDigestPattern.MatchString(ref.digest):
+		return ref, fmt.Errorf("invalid OCI reference %s, only sha256 digests are supported", s)
+	case ref.digest == "" && ref.tag == "":
+		return ref, fmt.Errorf("invalid OCI reference %s, a tag or a digest is required", s)
+	}
+	return ref, nil
+}
+
+// manifest returns the image manifest ref points to, the one of the running platform when ref
+// points to an index, and its digest.
+func (o *OCIInstaller) manifest(ref ociReference) (*ociManifest, string, error) {
+	reference := ref.tag
+	if ref.digest != "" {
+		reference = ref.digest
+	}
+	manifest, digest, err := o.fetchManifest(ref, reference)
+	if err != nil {
+		return nil, "", err
+	}
+	if ref.digest != "" && digest != ref.digest {
+		return nil, "", fmt.Errorf("digest mismatch for %s, got %s", ref, digest)
+	}
+	if len(manifest.Manifests) == 0 {
+		return manifest, digest, nil
+	}
+	var platforms []string
+	for _, m := range manifest.Manifests {
+		if m.Platform == nil {
+			continue
+		}
+		platforms = append(platforms, m.Platform.Os+"/"+m.Platform.Architecture)
+		if m.Platform.Os != Os || m.Platform.Architecture != runtime.GOARCH {
+			continue
+		}
+		platformManifest, platformDigest, err := o.fetchManifest(ref, m.Digest)
+		if err != nil {
+			return nil, "", err
+		}
+		if platformDigest != m.Digest {
+			return nil, "", fmt.Errorf("digest mismatch for the %s/%s manifest of %s, expected %s, got %s", Os, runtime.GOARCH, ref, m.Digest, platformDigest)
+		}
+		return platformManifest, platformDigest, nil
+	}
+	return nil, "", fmt.Errorf("%s has no manifest for %s/%s, only for %s", ref, Os, runtime.GOARCH, strings.Join(platforms, ", "))
+}
+
+func (o *OCIInstaller) fetchManifest(ref ociReference, reference string) (*ociManifest, string, error) {
+	resp, err := o.get(ref, o.url(ref, "manifests/"+reference), ociManifestAcceptMediaType)
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxOCIManifestSize))
+	if err != nil {
+		return nil, "", err
+	}
+	var manifest ociManifest
+	if err = json.Unmarshal(content, &manifest); err != nil {
+		return nil, "", fmt.Errorf("invalid manifest %s: %w", reference, err)
+	}
+	sum := sha256.Sum256(content)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	// A manifest pulled by tag has nothing else to be checked against.
+	if expected := resp.Header.Get("Docker-Content-Digest"); ociDigestPattern.MatchString(expected) && expected != digest {
+		return nil, "", fmt.Errorf("digest mismatch for manifest %s, the registry reported %s, got %s", reference, expected, digest)
+	}
+	return &manifest, digest, nil
+}
+
+// selectLayer picks the layer matching the layer pattern, or the one titled like the binary, or
+// the only layer, or the one titled after the running platform.
+func (o *OCIInstaller) selectLayer(manifest *ociManifest, version, binaryName string) (*ociDescriptor, error) {
+	if len(manifest.Layers) == 0 {
+		return nil, fmt.Errorf("the manifest has no layers")
+	}
+	title := func(layer ociDescriptor) string {
+		return layer.Annotations[ociTitleAnnotation]
+	}
+	var matchers []func(layer ociDescriptor) bool
+	if o.layerPattern != "" {
+		pattern, err := renderPattern(o.layerPattern, gitHubAssetArgument{
+			Version: version,
+			Os:      Os,
+			Arch:    runtime.GOARCH,
+		})
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, func(layer ociDescriptor) bool {
+			return pattern.MatchString(title(layer))
+		})
+	} else {
+		osRe, archRe := platformPatterns(Os, runtime.GOARCH)
+		matchers = append(matchers,
+			func(layer ociDescriptor) bool {
+				return title(layer) == binaryName
+			},
+			func(layer ociDescriptor) bool {
+				return len(manifest.Layers) == 1
+			},
+			func(layer ociDescriptor) bool {
+				return osRe.MatchString(title(layer)) && archRe.MatchString(title(layer))
+			})
+	}
+	for _, match := range matchers {
+		var matches []*ociDescriptor
+		for i, layer := range manifest.Layers {
+			if match(layer) {
+				matches = append(matches, &manifest.Layers[i])
+			}
+		}
+		switch len(matches) {
+		case 0:
+			continue
+		case 1:
+			return matches[0], nil
+		default:
+			var titles []string
+			for _, m := range matches {
+				titles = append(titles, title(*m))
+			}
+			return nil, fmt.Errorf("several layers match %s/%s: %s, pick one with a layer pattern", Os, runtime.GOARCH, strings.Join(titles, ", "))
+		}
+	}
+	return nil, fmt.Errorf("no layer matches %s/%s", Os, runtime.GOARCH)
+}
+
+// blob downloads the layer to dst and verifies its digest and size.
+func (o *OCIInstaller) blob(ref ociReference, layer *ociDescriptor, dst string) error {
+	if !ociDigestPattern.MatchString(layer.Digest) {
+		return fmt.Errorf("unsupported layer digest %s", layer.Digest)
+	}
+	resp, err := o.get(ref, o.url(ref, "blobs/"+layer.Digest), "application/octet-stream")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	f, err := os.Create(filepath.Clean(dst))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), resp.Body)
+	if err != nil {
+		return err
+	}
+	if digest := "sha256:" + hex.EncodeToString(h.Sum(nil)); digest != layer.Digest || (layer.Size > 0 && n != layer.Size) {
+		return fmt.Errorf("digest mismatch for layer %s, got %s of %d bytes", layer.Digest, digest, n)
+	}
+	return nil
+}
+
+func (o *OCIInstaller) url(ref ociReference, path string) string {
+	scheme := "https"
+	if o.plainHttp {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s/v2/%s/%s", scheme, ref.registry, ref.repository, path)
+}
+
+// get sends an authenticated GET. On a 401 it follows the registry's challenge, fetching a bearer
+// token for pulling the repository or falling back to basic auth, and tries once more.
+func (o *OCIInstaller) get(ref ociReference, rawUrl, accept string) (*http.Response, error) {
+	resp, err := o.send(rawUrl, accept, "")
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		_ = resp.Body.Close()
+		var authorization string
+		if authorization, err = o.authorize(ref, challenge); err != nil {
+			return nil, err
+		}
+		if resp, err = o.send(rawUrl, accept, authorization); err != nil {
+			return nil, err
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("failed to get %s: %s", rawUrl, resp.Status)
+	}
+	return resp, nil
+}
+
+// send bounds the request by the WithTimeout option, until the response body is closed.
+func (o *OCIInstaller) send(rawUrl, accept, authorization string) (*http.Response, error) {
+	ctx, cancel := o.requestContext()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawUrl, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	header, err := o.opts.header(rawUrl)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	req.Header = header
+	req.Header.Set("Accept", accept)
+	switch {
+	case authorization != "":
+		req.Header.Set("Authorization", authorization)
+	case o.token != "":
+		req.Header.Set("Authorization", "Bearer "+o.token)
+	}
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = cancelingBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+func (o *OCIInstaller) requestContext() (context.Context, context.CancelFunc) {
+	if o.opts.timeout > 0 {
+		return context.WithTimeout(o.ctx, o.opts.timeout)
+	}
+	return context.WithCancel(o.ctx)
+}
+
+// cancelingBody releases the request's context once the body is closed.
+type cancelingBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// authorize answers a WWW-Authenticate challenge with the Authorization header to retry with.
+func (o *OCIInstaller) authorize(ref ociReference, challenge string) (string, error) {
+	scheme, params := parseChallenge(challenge)
+	username, password, err := o.credentials(ref)
+	if err != nil {
+		return "", err
+	}
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if username == "" {
+			return "", fmt.Errorf("%s requires credentials", ref.registry)
+		}
+		req := &http.Request{Header: make(http.Header)}
+		req.SetBasicAuth(username, password)
+		return req.Header.Get("Authorization"), nil
+	case "bearer":
+	default:
+		return "", fmt.Errorf("%s refused the request without a supported authentication challenge", ref.registry)
+	}
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return "", fmt.Errorf("invalid token realm %s of %s", params["realm"], ref.registry)
+	}
+	query := realm.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = fmt.Sprintf("repository:%s:pull", ref.repository)
+	}
+	query.Set("scope", scope)
+	realm.RawQuery = query.Encode()
+	ctx, cancel := o.requestContext()
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get a token from %s: %s", realm.Host, resp.Status)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err = json.NewDecoder(io.LimitReader(resp.Body, maxOCIManifestSize)).Decode(&token); err != nil {
+		return "", fmt.Errorf("invalid token from %s: %w", realm.Host, err)
+	}
+	o.token = token.Token
+	if o.token == "" {
+		o.token = token.AccessToken
+	}
+	if o.token == "" {
+		return "", errors.New("the token service returned no token")
+	}
+	return "Bearer " + o.token, nil
+}
+
+// credentials returns the ones given with WithRegistryCredentials, or the netrc entry of the
+// registry host.
+func (o *OCIInstaller) credentials(ref ociReference) (string, string, error) {
+	if o.username != "" || o.opts.netrcFile == "" {
+		return o.username, o.password, nil
+	}
+	host := (&url.URL{Host: ref.registry}).Hostname()
+	return netrcCredentials(o.opts.netrcFile, host)
+}
+
+// parseChallenge splits `Bearer realm="...",service="..."` into its scheme and parameters.
+func parseChallenge(challenge string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	params := make(map[string]string)
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+		if strings.HasPrefix(rest, `"`) {
+			var ok bool
+			value, rest, ok = strings.Cut(rest[1:], `"`)
+			if !ok {
+				rest = ""
+			}
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		if key = strings.TrimSpace(key); key != "" {
+			params[strings.ToLower(key)] = value
+		}
+	}
+	return scheme, params
+}
+
+// layerFileName names the downloaded layer after its title, or after its media type so archives
+// are recognized and extracted.
+func layerFileName(layer *ociDescriptor) string {
+	if title := filepath.Base(layer.Annotations[ociTitleAnnotation]); title != "." && title != "/" && title != "" {
+		return title
+	}
+	switch {
+	case strings.HasSuffix(layer.MediaType, "tar+gzip"), strings.HasSuffix(layer.MediaType, ".tar.gzip"):
+		return "layer.tar.gz"
+	case strings.HasSuffix(layer.MediaType, "tar+zstd"):
+		return "layer.tar.zst"
+	case strings.HasSuffix(layer.MediaType, ".tar"):
+		return "layer.tar"
+	}
+	return "layer"
+}
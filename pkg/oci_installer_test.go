@@ -0,0 +1,378 @@
+package pkg_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lonegunmanb/genv/pkg"
+	"github.com/prashantv/gostub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	ociIndexType    = "application/vnd.oci.image.index.v1+json"
+	ociManifestType = "application/vnd.oci.image.manifest.v1+json"
+)
+
+// fakeRegistry stands in for an OCI distribution registry serving the team/tool repository. With
+// a token set, requests need a bearer token from its /token endpoint, which requires the
+// credentials when they are set.
+type fakeRegistry struct {
+	*httptest.Server
+	token    string
+	username string
+	password string
+	// stallBlobs sends half of every blob and then nothing until the client gives up.
+	stallBlobs bool
+
+	mu        sync.Mutex
+	blobs     map[string][]byte
+	manifests map[string][]byte
+	// digests holds the digest a manifest was pushed with, reported as Docker-Content-Digest.
+	digests map[string]string
+	tags    []string
+}
+
+func newFakeRegistry(t *testing.T) *fakeRegistry {
+	return startFakeRegistry(t, (*httptest.Server).Start)
+}
+
+func newFakeTLSRegistry(t *testing.T) *fakeRegistry {
+	return startFakeRegistry(t, (*httptest.Server).StartTLS)
+}
+
+func startFakeRegistry(t *testing.T, start func(*httptest.Server)) *fakeRegistry {
+	useOsFs(t)
+	stub := gostub.Stub(&pkg.Os, "linux")
+	t.Cleanup(stub.Reset)
+	r := &fakeRegistry{
+		blobs:     make(map[string][]byte),
+		manifests: make(map[string][]byte),
+		digests:   make(map[string]string),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /token", func(w http.ResponseWriter, req *http.Request) {
+		user, password, _ := req.BasicAuth()
+		if user != r.username || password != r.password || req.URL.Query().Get("scope") != "repository:team/tool:pull" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"token": r.token})
+	})
+	mux.HandleFunc("GET /v2/team/tool/manifests/{reference}", func(w http.ResponseWriter, req *http.Request) {
+		r.mu.Lock()
+		content, ok := r.manifests[req.PathValue("reference")]
+		digest := r.digests[req.PathValue("reference")]
+		r.mu.Unlock()
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		var manifest struct {
+			MediaType string `json:"mediaType"`
+		}
+		_ = json.Unmarshal(content, &manifest)
+		w.Header().Set("Content-Type", manifest.MediaType)
+		w.Header().Set("Docker-Content-Digest", digest)
+		_, _ = w.Write(content)
+	})
+	mux.HandleFunc("GET /v2/team/tool/blobs/{digest}", func(w http.ResponseWriter, req *http.Request) {
+		r.mu.Lock()
+		content, ok := r.blobs[req.PathValue("digest")]
+		r.mu.Unlock()
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		if r.stallBlobs {
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			_, _ = w.Write(content[:len(content)/2])
+			w.(http.Flusher).Flush()
+			select {
+			case <-req.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		_, _ = w.Write(content)
+	})
+	mux.HandleFunc("GET /v2/team/tool/tags/list", func(w http.ResponseWriter, req *http.Request) {
+		tags := r.tags
+		if last := req.URL.Query().Get("last"); last == "" && len(tags) > 2 {
+			tags = tags[:2]
+			w.Header().Set("Link", fmt.Sprintf(`</v2/team/tool/tags/list?n=2&last=%s>; rel="next"`, tags[1]))
+		} else if last != "" {
+			for i, tag := range tags {
+				if tag == last {
+					tags = tags[i+1:]
+					break
+				}
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"name": "team/tool", "tags": tags})
+	})
+	r.Server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if r.token != "" && req.URL.Path != "/token" && req.Header.Get("Authorization") != "Bearer "+r.token {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="fake-registry",scope="repository:team/tool:pull"`, r.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, req)
+	}))
+	start(r.Server)
+	t.Cleanup(r.Close)
+	return r
+}
+
+func ociDigest(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// pushBlob stores a layer and returns its descriptor.
+func (r *fakeRegistry) pushBlob(content []byte, mediaType, title string) map[string]any {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	digest := ociDigest(content)
+	r.blobs[digest] = content
+	descriptor := map[string]any{
+		"mediaType": mediaType,
+		"digest":    digest,
+		"size":      len(content),
+	}
+	if title != "" {
+		descriptor["annotations"] = map[string]string{"org.opencontainers.image.title": title}
+	}
+	return descriptor
+}
+
+// pushManifest stores a manifest under its digest and the tag, when there's one, and returns the
+// digest.
+func (r *fakeRegistry) pushManifest(tag string, manifest map[string]any) string {
+	content, _ := json.Marshal(manifest)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	digest := ociDigest(content)
+	r.manifests[digest] = content
+	r.digests[digest] = digest
+	if tag != "" {
+		r.manifests[tag] = content
+		r.digests[tag] = digest
+		r.tags = append(r.tags, tag)
+	}
+	return digest
+}
+
+func (r *fakeRegistry) artifact(tag string, layers ...map[string]any) string {
+	return r.pushManifest(tag, map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     ociManifestType,
+		"artifactType":  "application/vnd.example.tool",
+		"config":        r.pushBlob([]byte("{}"), "application/vnd.oci.empty.v1+json", ""),
+		"layers":        layers,
+	})
+}
+
+func (r *fakeRegistry) installer(t *testing.T, opts ...pkg.OCIOption) *pkg.OCIInstaller {
+	opts = append([]pkg.OCIOption{pkg.WithPlainHttp()}, opts...)
+	installer, err := pkg.NewOCIInstaller(strings.TrimPrefix(r.URL, "http://")+"/team/tool:{{ .Version }}", nil, opts...)
+	require.NoError(t, err)
+	return installer
+}
+
+func readBinary(t *testing.T, path string) string {
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	stat, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.NotZero(t, stat.Mode()&0100, "the binary is executable")
+	return string(content)
+}
+
+func TestOCIInstaller_Artifact(t *testing.T) {
+	r := newFakeRegistry(t)
+	digest := r.artifact("1.0.0", r.pushBlob([]byte("tool 1.0.0"), "application/octet-stream", "tool"))
+	dst := filepath.Join(t.TempDir(), "1.0.0", "tool")
+	info, err := r.installer(t).InstallWithInfo("1.0.0", dst)
+	require.NoError(t, err)
+	assert.Equal(t, "tool 1.0.0", readBinary(t, dst))
+	assert.Equal(t, pkg.OCIInstallerName, info.Installer)
+	assert.Equal(t, strings.TrimPrefix(r.URL, "http://")+"/team/tool@"+digest, info.Source, "the source is pinned to the manifest digest")
+}
+
+func TestOCIInstaller_IndexSelectsPlatform(t *testing.T) {
+	r := newFakeRegistry(t)
+	otherArch := "s390x"
+	archive := tarGz(t, map[string]string{"tool_linux/tool": "tool for linux"})
+	platformDigest := r.artifact("",
+		r.pushBlob(archive, "application/vnd.oci.image.layer.v1.tar+gzip", fmt.Sprintf("tool_linux_%s.tar.gz", runtime.GOARCH)),
+		r.pushBlob([]byte("docs"), "text/markdown", "README.md"))
+	otherDigest := r.artifact("", r.pushBlob([]byte("tool for s390x"), "application/octet-stream", "tool"))
+	r.pushManifest("1.0.0", map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     ociIndexType,
+		"manifests": []map[string]any{
+			{"mediaType": ociManifestType, "digest": otherDigest, "size": 1, "platform": map[string]string{"os": "linux", "architecture": otherArch}},
+			{"mediaType": ociManifestType, "digest": platformDigest, "size": 1, "platform": map[string]string{"os": "linux", "architecture": runtime.GOARCH}},
+		},
+	})
+	dst := filepath.Join(t.TempDir(), "tool")
+	info, err := r.installer(t).InstallWithInfo("1.0.0", dst)
+	require.NoError(t, err)
+	assert.Equal(t, "tool for linux", readBinary(t, dst), "the layer of the platform is picked and extracted")
+	assert.True(t, strings.HasSuffix(info.Source, "@"+platformDigest))
+
+	stub := gostub.Stub(&pkg.Os, "windows")
+	defer stub.Reset()
+	err = r.installer(t).Install("1.0.0", filepath.Join(t.TempDir(), "tool.exe"))
+	assert.ErrorContains(t, err, fmt.Sprintf("has no manifest for windows/%s, only for linux/%s, linux/%s", runtime.GOARCH, otherArch, runtime.GOARCH))
+}
+
+func TestOCIInstaller_PullByDigest(t *testing.T) {
+	r := newFakeRegistry(t)
+	digest := r.artifact("", r.pushBlob([]byte("pinned tool"), "application/octet-stream", "tool"))
+	dst := filepath.Join(t.TempDir(), "tool")
+	require.NoError(t, r.installer(t).Install(digest, dst))
+	assert.Equal(t, "pinned tool", readBinary(t, dst))
+
+	// A registry serving other content under a digest is caught.
+	r.manifests[digest] = r.manifests[r.artifact("", r.pushBlob([]byte("evil tool"), "application/octet-stream", "tool"))]
+	err := r.installer(t).Install(digest, filepath.Join(t.TempDir(), "tool"))
+	assert.ErrorContains(t, err, "digest mismatch")
+}
+
+func TestOCIInstaller_TamperedManifest(t *testing.T) {
+	r := newFakeRegistry(t)
+	r.artifact("1.0.0", r.pushBlob([]byte("tool 1.0.0"), "application/octet-stream", "tool"))
+	r.manifests["1.0.0"] = r.manifests[r.artifact("", r.pushBlob([]byte("evil tool"), "application/octet-stream", "tool"))]
+	err := r.installer(t).Install("1.0.0", filepath.Join(t.TempDir(), "tool"))
+	assert.ErrorContains(t, err, "digest mismatch for manifest 1.0.0")
+}
+
+func TestOCIInstaller_TamperedLayer(t *testing.T) {
+	r := newFakeRegistry(t)
+	layer := r.pushBlob([]byte("tool 1.0.0"), "application/octet-stream", "tool")
+	r.artifact("1.0.0", layer)
+	r.blobs[layer["digest"].(string)] = []byte("evil tool!")
+	err := r.installer(t).Install("1.0.0", filepath.Join(t.TempDir(), "tool"))
+	assert.ErrorContains(t, err, "digest mismatch for layer")
+}
+
+func TestOCIInstaller_LayerSelection(t *testing.T) {
+	r := newFakeRegistry(t)
+	r.artifact("1.0.0",
+		r.pushBlob([]byte("linux tool"), "application/octet-stream", fmt.Sprintf("tool-linux-%s", runtime.GOARCH)),
+		r.pushBlob([]byte("linux musl tool"), "application/octet-stream", fmt.Sprintf("tool-linux-%s-musl", runtime.GOARCH)),
+		r.pushBlob([]byte("darwin tool"), "application/octet-stream", fmt.Sprintf("tool-darwin-%s", runtime.GOARCH)))
+	err := r.installer(t).Install("1.0.0", filepath.Join(t.TempDir(), "tool"))
+	assert.ErrorContains(t, err, "several layers match linux/"+runtime.GOARCH)
+
+	dst := filepath.Join(t.TempDir(), "tool")
+	require.NoError(t, r.installer(t, pkg.WithLayerPattern(`-{{ .Os }}-{{ .Arch }}-musl$`)).Install("1.0.0", dst))
+	assert.Equal(t, "linux musl tool", readBinary(t, dst))
+}
+
+func TestOCIInstaller_TokenAuth(t *testing.T) {
+	r := newFakeRegistry(t)
+	r.token, r.username, r.password = "t0ken", "robot", "s3cret"
+	r.artifact("1.0.0", r.pushBlob([]byte("private tool"), "application/octet-stream", "tool"))
+
+	err := r.installer(t).Install("1.0.0", filepath.Join(t.TempDir(), "tool"))
+	assert.ErrorContains(t, err, "failed to get a token")
+
+	dst := filepath.Join(t.TempDir(), "tool")
+	require.NoError(t, r.installer(t, pkg.WithRegistryCredentials("robot", "s3cret")).Install("1.0.0", dst))
+	assert.Equal(t, "private tool", readBinary(t, dst))
+}
+
+func TestOCIInstaller_NetrcCredentials(t *testing.T) {
+	r := newFakeRegistry(t)
+	r.token, r.username, r.password = "t0ken", "robot", "s3cret"
+	r.artifact("1.0.0", r.pushBlob([]byte("private tool"), "application/octet-stream", "tool"))
+	netrc := filepath.Join(t.TempDir(), ".netrc")
+	require.NoError(t, os.WriteFile(netrc, []byte("machine 127.0.0.1 login robot password s3cret\n"), 0600))
+
+	dst := filepath.Join(t.TempDir(), "tool")
+	require.NoError(t, r.installer(t, pkg.WithOCIDownloadOptions(pkg.WithNetrc(netrc))).Install("1.0.0", dst))
+	assert.Equal(t, "private tool", readBinary(t, dst))
+}
+
+func TestOCIInstaller_PrivateRegistryTool(t *testing.T) {
+	r := newFakeTLSRegistry(t)
+	r.token, r.username, r.password = "t0ken", "robot", "s3cret"
+	r.artifact("1.0.0", r.pushBlob([]byte("private tool"), "application/octet-stream", "tool"))
+	t.Setenv("TOOL_REGISTRY_TOKEN", "s3cret")
+	definition, err := json.Marshal(map[string]any{
+		"binaries":          []string{"tool"},
+		"oci_ref":           strings.TrimPrefix(r.URL, "https://") + "/team/tool:{{ .Version }}",
+		"ca_bundle":         caBundle(t, r.Server),
+		"registry_username": "robot",
+		"registry_password": `{{ env "TOOL_REGISTRY_TOKEN" }}`,
+	})
+	require.NoError(t, err)
+	registryDir, homeDir := t.TempDir(), t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(registryDir, "tool.json"), definition, 0644))
+	registry, err := pkg.LoadRegistry(registryDir)
+	require.NoError(t, err)
+	env, err := registry.Env("tool", homeDir, context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, env.Install("1.0.0"))
+	assert.Equal(t, "private tool", readBinary(t, filepath.Join(homeDir, "tool", "1.0.0", "tool")))
+}
+
+func TestOCIInstaller_Timeout(t *testing.T) {
+	r := newFakeRegistry(t)
+	r.token = "anonymous"
+	r.artifact("1.0.0", r.pushBlob([]byte("tool 1.0.0"), "application/octet-stream", "tool"))
+	timeout := pkg.WithOCIDownloadOptions(pkg.WithTimeout(time.Second), pkg.WithReadTimeout(0))
+
+	dst := filepath.Join(t.TempDir(), "tool")
+	require.NoError(t, r.installer(t, timeout).Install("1.0.0", dst), "the timeout lasts until the blob is read")
+	assert.Equal(t, "tool 1.0.0", readBinary(t, dst))
+
+	r.stallBlobs = true
+	start := time.Now()
+	err := r.installer(t, timeout).Install("1.0.0", filepath.Join(t.TempDir(), "tool"))
+	assert.ErrorContains(t, err, "context deadline exceeded")
+	assert.Less(t, time.Since(start), 3*time.Second)
+}
+
+func TestOCIInstaller_ListRemote(t *testing.T) {
+	r := newFakeRegistry(t)
+	r.token = "anonymous"
+	for _, tag := range []string{"1.0.0", "1.1.0", "2.0.0"} {
+		r.artifact(tag, r.pushBlob([]byte("tool "+tag), "application/octet-stream", "tool"))
+	}
+	tags, err := r.installer(t).ListRemote()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.0.0", "1.1.0", "2.0.0"}, tags)
+}
+
+func TestNewOCIInstaller_InvalidReference(t *testing.T) {
+	for desc, ref := range map[string]string{
+		"upper_case":   "ghcr.io/Team/tool:{{ .Version }}",
+		"no_tag":       "ghcr.io/team/tool",
+		"bad_digest":   "ghcr.io/team/tool@md5:{{ .Version }}",
+		"bad_template": "ghcr.io/team/tool:{{ .Unknown }}",
+	} {
+		t.Run(desc, func(t *testing.T) {
+			_, err := pkg.NewOCIInstaller(ref, nil)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -1,5 +1,102 @@
 package pkg
 
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/spf13/afero"
+)
+
+// ProfileSchemaVersion is the schema version of the profile written by this package.
+const ProfileSchemaVersion = 1
+
+var ErrCorruptProfile = errors.New("corrupt profile")
+
 type Profile struct {
-	Version *string `json:"version"`
+	SchemaVersion int     `json:"schema_version"`
+	Version       *string `json:"version"`
+}
+
+// profileMigrations upgrade a raw profile by one schema version, profileMigrations[i] turns
+// schema version i into i+1. Append a migration here whenever Profile changes incompatibly.
+var profileMigrations = []func(raw map[string]json.RawMessage) error{
+	// 0 -> 1: legacy profiles only carry "version" which is kept as is.
+	func(raw map[string]json.RawMessage) error {
+		return nil
+	},
+}
+
+// parseProfile decodes the profile content and migrates it to ProfileSchemaVersion, errors
+// caused by malformed content wrap ErrCorruptProfile.
+func parseProfile(content []byte) (*Profile, error) {
+	if len(bytes.TrimSpace(content)) == 0 {
+		return &Profile{SchemaVersion: ProfileSchemaVersion}, nil
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(content, &raw); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrCorruptProfile, err.Error())
+	}
+	if raw == nil {
+		return nil, fmt.Errorf("%w: not a json object", ErrCorruptProfile)
+	}
+	schemaVersion := 0
+	if v, ok := raw["schema_version"]; ok {
+		if err := json.Unmarshal(v, &schemaVersion); err != nil {
+			return nil, fmt.Errorf("%w: invalid schema_version: %s", ErrCorruptProfile, err.Error())
+		}
+	}
+	if schemaVersion < 0 {
+		return nil, fmt.Errorf("%w: invalid schema_version %d", ErrCorruptProfile, schemaVersion)
+	}
+	if schemaVersion > ProfileSchemaVersion {
+		return nil, fmt.Errorf("profile schema version %d is newer than supported version %d, please upgrade", schemaVersion, ProfileSchemaVersion)
+	}
+	for ; schemaVersion < ProfileSchemaVersion; schemaVersion++ {
+		if err := profileMigrations[schemaVersion](raw); err != nil {
+			return nil, fmt.Errorf("cannot migrate profile from schema version %d: %w", schemaVersion, err)
+		}
+	}
+	raw["schema_version"] = json.RawMessage(strconv.Itoa(ProfileSchemaVersion))
+	migrated, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var profile Profile
+	if err = json.Unmarshal(migrated, &profile); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrCorruptProfile, err.Error())
+	}
+	return &profile, nil
+}
+
+// writeFileAtomic writes content to a temp file next to path then renames it, so readers
+// never observe a partially written file.
+func writeFileAtomic(path string, content []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := Fs.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := afero.TempFile(Fs, dir, fmt.Sprintf("%s.*.tmp", filepath.Base(path)))
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer func() {
+		_ = Fs.Remove(tmpName)
+	}()
+	if _, err = tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = Fs.Chmod(tmpName, perm); err != nil {
+		return err
+	}
+	return Fs.Rename(tmpName, path)
 }
@@ -0,0 +1,188 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+const (
+	// ProjectManifestName is genv's own project manifest, a TOML file with a [tools] table.
+	ProjectManifestName = ".genv.toml"
+	// ToolVersionsName is the asdf style manifest, one "tool version" pair per line.
+	ToolVersionsName = ".tool-versions"
+)
+
+// ToolVersion is a tool pinned to a version by a project manifest.
+type ToolVersion struct {
+	Tool    string
+	Version string
+}
+
+// FindProjectManifest returns the nearest project manifest in dir or its parents, .genv.toml wins
+// over .tool-versions in the same directory. An empty path means there is none.
+func FindProjectManifest(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		for _, name := range []string{ProjectManifestName, ToolVersionsName} {
+			path := filepath.Join(dir, name)
+			exists, err := afero.Exists(Fs, path)
+			if err != nil {
+				return "", err
+			}
+			if exists {
+				return path, nil
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// LoadProjectManifest reads the tools pinned by a .genv.toml or .tool-versions file, the format
+// is picked by the file name.
+func LoadProjectManifest(path string) ([]ToolVersion, error) {
+	content, err := afero.ReadFile(Fs, path)
+	if err != nil {
+		return nil, err
+	}
+	var tools []ToolVersion
+	if filepath.Base(path) == ToolVersionsName {
+		tools = parseToolVersions(content)
+	} else if tools, err = parseGenvToml(content); err != nil {
+		return nil, fmt.Errorf("invalid project manifest %s: %w", path, err)
+	}
+	seen := make(map[string]bool)
+	for _, t := range tools {
+		if seen[t.Tool] {
+			return nil, fmt.Errorf("invalid project manifest %s: tool %s is pinned more than once", path, t.Tool)
+		}
+		seen[t.Tool] = true
+	}
+	return tools, nil
+}
+
+// parseToolVersions reads asdf's .tool-versions format, when a line lists fallback versions
+// only the first one is used.
+func parseToolVersions(content []byte) []ToolVersion {
+	var tools []ToolVersion
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		tools = append(tools, ToolVersion{
+			Tool:    fields[0],
+			Version: fields[1],
+		})
+	}
+	return tools
+}
+
+// parseGenvToml reads the subset of TOML used by .genv.toml, string pairs in a [tools] table:
+//
+//	[tools]
+//	vault = "1.6.0"
+//	terraform = "1.5.7"
+//
+// Other tables are ignored so the file can grow without breaking older readers.
+func parseGenvToml(content []byte) ([]ToolVersion, error) {
+	var tools []ToolVersion
+	table := ""
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: invalid table header %q", lineNumber, line)
+			}
+			table = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		if table != "tools" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected tool = \"version\", got %q", lineNumber, line)
+		}
+		tool, err := tomlKey(strings.TrimSpace(key))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		version, err := tomlString(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		tools = append(tools, ToolVersion{
+			Tool:    tool,
+			Version: version,
+		})
+	}
+	return tools, scanner.Err()
+}
+
+func tomlKey(key string) (string, error) {
+	if strings.HasPrefix(key, `"`) {
+		return tomlString(key)
+	}
+	if key == "" || strings.ContainsAny(key, " \t\"'#") {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	return key, nil
+}
+
+// tomlString unquotes a basic or literal string followed by an optional comment.
+func tomlString(value string) (string, error) {
+	if strings.HasPrefix(value, "'") {
+		end := strings.Index(value[1:], "'")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated string %s", value)
+		}
+		return value[1 : end+1], checkTrailing(value[end+2:])
+	}
+	if !strings.HasPrefix(value, `"`) {
+		return "", fmt.Errorf("version must be a quoted string, got %s", value)
+	}
+	for end := 1; end < len(value); end++ {
+		if value[end] == '\\' {
+			end++
+			continue
+		}
+		if value[end] == '"' {
+			s, err := strconv.Unquote(value[:end+1])
+			if err != nil {
+				return "", fmt.Errorf("invalid string %s: %w", value[:end+1], err)
+			}
+			return s, checkTrailing(value[end+1:])
+		}
+	}
+	return "", fmt.Errorf("unterminated string %s", value)
+}
+
+func checkTrailing(rest string) error {
+	rest = strings.TrimSpace(rest)
+	if rest != "" && !strings.HasPrefix(rest, "#") {
+		return fmt.Errorf("unexpected %q after value", rest)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package pkg_test
+
+import (
+	"github.com/lonegunmanb/genv/pkg"
+)
+
+func (d *envSuite) TestLoadProjectManifest() {
+	cases := []struct {
+		desc     string
+		path     string
+		content  string
+		expected []pkg.ToolVersion
+	}{
+		{
+			desc: "genv_toml",
+			path: "/src/project/.genv.toml",
+			content: `# tools used by this project
+[settings]
+parallel = 4
+
+[tools]
+vault = "1.6.0"
+"terraform" = '1.5.7' # pinned for the provider
+consul = "1.17.0"
+`,
+			expected: []pkg.ToolVersion{
+				{Tool: "vault", Version: "1.6.0"},
+				{Tool: "terraform", Version: "1.5.7"},
+				{Tool: "consul", Version: "1.17.0"},
+			},
+		},
+		{
+			desc: "tool_versions",
+			path: "/src/project/.tool-versions",
+			content: `# asdf
+vault 1.6.0
+terraform 1.5.7 1.5.6 # fallback versions are ignored
+
+`,
+			expected: []pkg.ToolVersion{
+				{Tool: "vault", Version: "1.6.0"},
+				{Tool: "terraform", Version: "1.5.7"},
+			},
+		},
+	}
+	for _, c := range cases {
+		cc := c
+		d.Run(cc.desc, func() {
+			d.files(map[string][]byte{
+				cc.path: []byte(cc.content),
+			})
+			tools, err := pkg.LoadProjectManifest(cc.path)
+			d.NoError(err)
+			d.Equal(cc.expected, tools)
+		})
+	}
+}
+
+func (d *envSuite) TestLoadProjectManifest_Invalid() {
+	cases := map[string]string{
+		"unquoted_version":  "[tools]\nvault = 1.6.0\n",
+		"missing_value":     "[tools]\nvault\n",
+		"unterminated":      "[tools]\nvault = \"1.6.0\n",
+		"trailing_garbage":  "[tools]\nvault = \"1.6.0\" x\n",
+		"invalid_header":    "[tools\nvault = \"1.6.0\"\n",
+		"duplicated_tool":   "[tools]\nvault = \"1.6.0\"\nvault = \"1.7.0\"\n",
+		"invalid_bare_key":  "[tools]\nva ult = \"1.6.0\"\n",
+		"invalid_quote_key": "[tools]\n\"vault = \"1.6.0\"\n",
+	}
+	for desc, content := range cases {
+		d.Run(desc, func() {
+			d.files(map[string][]byte{
+				"/src/.genv.toml": []byte(content),
+			})
+			_, err := pkg.LoadProjectManifest("/src/.genv.toml")
+			d.Error(err)
+		})
+	}
+}
+
+func (d *envSuite) TestFindProjectManifest() {
+	d.files(map[string][]byte{
+		"/src/.tool-versions":          []byte("vault 1.6.0"),
+		"/src/project/.genv.toml":      []byte("[tools]\n"),
+		"/src/project/.tool-versions":  []byte("vault 1.6.0"),
+		"/src/other/nested/readme.txt": []byte(""),
+	})
+	for dir, expected := range map[string]string{
+		"/src/project":       "/src/project/.genv.toml",
+		"/src/project/sub":   "/src/project/.genv.toml",
+		"/src/other/nested":  "/src/.tool-versions",
+		"/elsewhere/project": "",
+	} {
+		path, err := pkg.FindProjectManifest(dir)
+		d.NoError(err)
+		d.Equal(expected, path, dir)
+	}
+}
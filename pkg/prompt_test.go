@@ -0,0 +1,29 @@
+package pkg_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/lonegunmanb/genv/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfirm(t *testing.T) {
+	cases := map[string]bool{
+		"y\n":   true,
+		"YES\n": true,
+		" yes ": true,
+		"n\n":   false,
+		"\n":    false,
+		"":      false,
+		"nope":  false,
+	}
+	for input, expected := range cases {
+		var out bytes.Buffer
+		ok, err := pkg.Confirm(strings.NewReader(input), &out, "Uninstall 1.6.0?")
+		assert.NoError(t, err)
+		assert.Equal(t, expected, ok, input)
+		assert.Equal(t, "Uninstall 1.6.0? [y/N] ", out.String())
+	}
+}
@@ -0,0 +1,121 @@
+package pkg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/spf13/afero"
+)
+
+type PruneOptions struct {
+	// KeepLatest keeps the N highest semver versions, versions that aren't semver don't count as latest.
+	KeepLatest int
+	// KeepReferencedUnder keeps versions pinned by a version file found anywhere under these roots.
+	KeepReferencedUnder []string
+	// UnusedFor only removes versions that haven't been used for longer than this duration.
+	UnusedFor time.Duration
+	// DryRun reports the versions that would be removed without removing them.
+	DryRun bool
+}
+
+// Prune removes installed versions that aren't kept by any of the policies in opts and returns
+// them. The active version is always kept. At least one policy must be set, so a bare Prune
+// won't wipe the env.
+func (env *Env) Prune(opts PruneOptions) ([]string, error) {
+	if opts.KeepLatest <= 0 && len(opts.KeepReferencedUnder) == 0 && opts.UnusedFor <= 0 {
+		return nil, fmt.Errorf("at least one prune policy is required")
+	}
+	if err := env.lock(); err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = env.unlock()
+	}()
+	installed, err := env.ListInstalled()
+	if err != nil {
+		return nil, err
+	}
+	keep := make(map[string]bool)
+	current, err := env.CurrentVersion()
+	if err != nil {
+		return nil, err
+	}
+	if current != nil {
+		keep[*current] = true
+	}
+	if opts.KeepLatest > 0 {
+		var semvers []string
+		for _, v := range installed {
+			if _, err := semver.NewVersion(v); err == nil {
+				semvers = append(semvers, v)
+			}
+		}
+		// installed is sorted already, the latest versions are at the tail.
+		for i := len(semvers) - 1; i >= 0 && i >= len(semvers)-opts.KeepLatest; i-- {
+			keep[semvers[i]] = true
+		}
+	}
+	for _, root := range opts.KeepReferencedUnder {
+		referenced, err := env.referencedVersions(root)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range referenced {
+			keep[v] = true
+		}
+	}
+	var pruned []string
+	for _, v := range installed {
+		if keep[v] {
+			continue
+		}
+		if opts.UnusedFor > 0 {
+			lastUsed, err := env.LastUsed(v)
+			if err != nil {
+				return nil, err
+			}
+			if time.Since(lastUsed) < opts.UnusedFor {
+				continue
+			}
+		}
+		if !opts.DryRun {
+			if err = Fs.RemoveAll(env.versionDir(v)); err != nil {
+				return pruned, err
+			}
+		}
+		pruned = append(pruned, v)
+	}
+	return pruned, nil
+}
+
+// referencedVersions returns versions pinned by version files under root, unreadable
+// directories are skipped.
+func (env *Env) referencedVersions(root string) ([]string, error) {
+	var versions []string
+	err := afero.Walk(Fs, root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if info != nil && info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" || info.Name() == "node_modules" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Name() != env.VersionFileName() {
+			return nil
+		}
+		version, err := readVersionFile(path)
+		if err == nil && version != "" {
+			versions = append(versions, version)
+		}
+		return nil
+	})
+	return versions, err
+}
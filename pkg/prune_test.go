@@ -0,0 +1,87 @@
+package pkg_test
+
+import (
+	"time"
+
+	"github.com/lonegunmanb/genv/pkg"
+	"github.com/spf13/afero"
+)
+
+func (d *envSuite) installedVersions(versions ...string) {
+	for _, v := range versions {
+		d.files(map[string][]byte{
+			"/tmp/tfenv/" + v + "/terraform": []byte("fake"),
+		})
+	}
+}
+
+func (d *envSuite) TestPrune_RequirePolicy() {
+	d.installedVersions("v1.0.0")
+	sut := pkg.NewEnv("/tmp", "tfenv", "terraform", nil)
+	_, err := sut.Prune(pkg.PruneOptions{})
+	d.Error(err)
+}
+
+func (d *envSuite) TestPrune_KeepLatest() {
+	d.installedVersions("v1.0.0", "v1.1.0", "v1.10.0", "v1.2.0", "c05e704f072ce244170d80b0d7abb09c86def826")
+	sut := pkg.NewEnv("/tmp", "tfenv", "terraform", nil)
+	pruned, err := sut.Prune(pkg.PruneOptions{KeepLatest: 2})
+	d.NoError(err)
+	d.Equal([]string{"v1.0.0", "v1.1.0", "c05e704f072ce244170d80b0d7abb09c86def826"}, pruned)
+	installed, err := sut.ListInstalled()
+	d.NoError(err)
+	d.Equal([]string{"v1.2.0", "v1.10.0"}, installed)
+}
+
+func (d *envSuite) TestPrune_KeepActiveVersion() {
+	d.installedVersions("v1.0.0", "v1.1.0", "v1.2.0")
+	d.files(map[string][]byte{
+		"/tmp/tfenv/.profile.json": []byte(`{"version":"v1.0.0"}`),
+	})
+	sut := pkg.NewEnv("/tmp", "tfenv", "terraform", nil)
+	pruned, err := sut.Prune(pkg.PruneOptions{KeepLatest: 1})
+	d.NoError(err)
+	d.Equal([]string{"v1.1.0"}, pruned)
+}
+
+func (d *envSuite) TestPrune_KeepReferenced() {
+	d.installedVersions("v1.0.0", "v1.1.0", "v1.2.0")
+	d.files(map[string][]byte{
+		"/src/project1/.terraform-version":          []byte("v1.0.0\n"),
+		"/src/nested/project2/.terraform-version":   []byte("v1.1.0"),
+		"/src/project3/.git/.terraform-version":     []byte("v1.2.0"),
+		"/elsewhere/project4/.terraform-version":    []byte("v1.2.0"),
+		"/src/project5/.terraform-version.disabled": []byte("v1.2.0"),
+	})
+	sut := pkg.NewEnv("/tmp", "tfenv", "terraform", nil)
+	pruned, err := sut.Prune(pkg.PruneOptions{KeepReferencedUnder: []string{"/src"}})
+	d.NoError(err)
+	d.Equal([]string{"v1.2.0"}, pruned)
+}
+
+func (d *envSuite) TestPrune_UnusedFor() {
+	d.installedVersions("v1.0.0", "v1.1.0", "v1.2.0")
+	now := time.Now()
+	d.files(map[string][]byte{
+		"/tmp/tfenv/v1.0.0/.usage": {},
+		"/tmp/tfenv/v1.1.0/.usage": {},
+	})
+	d.Require().NoError(d.mockFs.Chtimes("/tmp/tfenv/v1.0.0/.usage", now, now.Add(-40*24*time.Hour)))
+	d.Require().NoError(d.mockFs.Chtimes("/tmp/tfenv/v1.1.0/.usage", now, now.Add(-time.Hour)))
+	sut := pkg.NewEnv("/tmp", "tfenv", "terraform", nil)
+	pruned, err := sut.Prune(pkg.PruneOptions{UnusedFor: 30 * 24 * time.Hour})
+	d.NoError(err)
+	// v1.2.0 has never been used, it was installed just now.
+	d.Equal([]string{"v1.0.0"}, pruned)
+}
+
+func (d *envSuite) TestPrune_DryRun() {
+	d.installedVersions("v1.0.0", "v1.1.0")
+	sut := pkg.NewEnv("/tmp", "tfenv", "terraform", nil)
+	pruned, err := sut.Prune(pkg.PruneOptions{KeepLatest: 1, DryRun: true})
+	d.NoError(err)
+	d.Equal([]string{"v1.0.0"}, pruned)
+	exists, err := afero.Exists(d.mockFs, "/tmp/tfenv/v1.0.0/terraform")
+	d.NoError(err)
+	d.True(exists)
+}
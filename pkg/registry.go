@@ -0,0 +1,246 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// RegistryDirName is the default registry directory in the genv home dir, next to the env dirs of
+// the tools, so no tool can be named after it.
+const RegistryDirName = "registry"
+
+// ToolDefinition describes a tool managed by the genv runtime, it's loaded from <registry>/<name>.json.
+type ToolDefinition struct {
+	// Name defaults to the file name without the .json extension.
+	Name                string   `json:"name"`
+	Binaries            []string `json:"binaries"`
+	DownloadUrlTemplate string   `json:"download_url_template,omitempty"`
+	GitRepo             string   `json:"git_repo,omitempty"`
+	GitSubFolder        string   `json:"git_sub_folder,omitempty"`
+	// GitHubRepo, as owner/name, installs the tool from the assets of its GitHub releases, see
+	// GitHubReleaseInstaller. AssetPattern and ChecksumAsset are regular expression templates,
+	// GitHubApiUrl points to a GitHub Enterprise server's API.
+	GitHubRepo    string `json:"github_repo,omitempty"`
+	AssetPattern  string `json:"asset_pattern,omitempty"`
+	ChecksumAsset string `json:"checksum_asset,omitempty"`
+	GitHubApiUrl  string `json:"github_api_url,omitempty"`
+	// OCIRef, like ghcr.io/org/tool:{{ .Version }}, pulls the tool from an OCI registry, see
+	// OCIInstaller. LayerPattern is a regular expression template. RegistryUsername and
+	// RegistryPassword, templates that can read env vars like headers, authenticate to the
+	// registry, the netrc entry of the registry host is used without them.
+	OCIRef           string `json:"oci_ref,omitempty"`
+	LayerPattern     string `json:"layer_pattern,omitempty"`
+	RegistryUsername string `json:"registry_username,omitempty"`
+	RegistryPassword string `json:"registry_password,omitempty"`
+	// Headers are sent with every download, values are templates that can read env vars like
+	// `Bearer {{ env "ARTIFACTORY_TOKEN" }}`.
+	Headers  map[string]string `json:"headers,omitempty"`
+	CABundle string            `json:"ca_bundle,omitempty"`
+	Netrc    string            `json:"netrc,omitempty"`
+}
+
+// Registry holds the tool definitions found in a registry directory, adding a tool only takes a new json file.
+type Registry struct {
+	dir   string
+	tools map[string]ToolDefinition
+}
+
+func LoadRegistry(dir string) (*Registry, error) {
+	r := &Registry{
+		dir:   dir,
+		tools: make(map[string]ToolDefinition),
+	}
+	files, err := afero.Glob(Fs, filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		content, err := afero.ReadFile(Fs, file)
+		if err != nil {
+			return nil, err
+		}
+		var tool ToolDefinition
+		if err = json.Unmarshal(content, &tool); err != nil {
+			return nil, fmt.Errorf("invalid tool definition %s: %w", file, err)
+		}
+		if tool.Name == "" {
+			tool.Name = strings.TrimSuffix(filepath.Base(file), ".json")
+		}
+		if err = tool.validate(); err != nil {
+			return nil, fmt.Errorf("invalid tool definition %s: %w", file, err)
+		}
+		if _, ok := r.tools[tool.Name]; ok {
+			return nil, fmt.Errorf("tool %s is defined more than once in %s", tool.Name, dir)
+		}
+		r.tools[tool.Name] = tool
+	}
+	return r, nil
+}
+
+// Tools returns the names of all registered tools in lexical order.
+func (r *Registry) Tools() []string {
+	var names []string
+	for name := range r.tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *Registry) Tool(name string) (*ToolDefinition, error) {
+	tool, ok := r.tools[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown tool %s, no definition found in %s", name, r.dir)
+	}
+	return &tool, nil
+}
+
+// ToolForBinary returns the tool that ships the given binary, it's how a shim finds its tool.
+func (r *Registry) ToolForBinary(binary string) (*ToolDefinition, bool) {
+	for _, name := range r.Tools() {
+		tool := r.tools[name]
+		if slices.Contains(tool.Binaries, binary) {
+			return &tool, true
+		}
+	}
+	return nil, false
+}
+
+// Env creates the env of the given tool, its versions live in homeDir/<tool>.
+func (r *Registry) Env(name string, homeDir string, ctx context.Context) (*Env, error) {
+	tool, err := r.Tool(name)
+	if err != nil {
+		return nil, err
+	}
+	installer, err := tool.Installer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	env, err := NewMultiBinaryEnv(homeDir, tool.Name, tool.Binaries, installer)
+	if err != nil {
+		return nil, err
+	}
+	if tool.Name != env.BinaryName() {
+		// .tool-versions and project manifests refer to the tool by its name.
+		env.SetAliases(tool.Name)
+	}
+	return env, nil
+}
+
+// Installer downloads the tool when a download url template is defined, then tries its GitHub
+// releases and its OCI registry and builds it from the git repository last, each one falls back
+// to the next.
+func (t *ToolDefinition) Installer(ctx context.Context) (Installer, error) {
+	var installers []Installer
+	if t.DownloadUrlTemplate != "" {
+		d, err := NewDownloadInstaller(t.DownloadUrlTemplate, ctx, t.downloadOptions()...)
+		if err != nil {
+			return nil, err
+		}
+		installers = append(installers, d)
+	}
+	if t.GitHubRepo != "" {
+		// Headers are meant for the download host, GitHub authenticates with GITHUB_TOKEN.
+		opts := []GitHubReleaseOption{WithGitHubDownloadOptions(t.transportOptions()...)}
+		if t.GitHubApiUrl != "" {
+			opts = append(opts, WithGitHubApiUrl(t.GitHubApiUrl))
+		}
+		if t.AssetPattern != "" {
+			opts = append(opts, WithAssetPattern(t.AssetPattern))
+		}
+		if t.ChecksumAsset != "" {
+			opts = append(opts, WithChecksumAsset(t.ChecksumAsset))
+		}
+		g, err := NewGitHubReleaseInstaller(t.GitHubRepo, ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+		installers = append(installers, g)
+	}
+	if t.OCIRef != "" {
+		opts := []OCIOption{WithOCIDownloadOptions(t.transportOptions()...)}
+		if t.LayerPattern != "" {
+			opts = append(opts, WithLayerPattern(t.LayerPattern))
+		}
+		if t.RegistryUsername != "" {
+			username, err := renderEnvTemplate("registry_username", t.RegistryUsername)
+			if err != nil {
+				return nil, fmt.Errorf("invalid registry_username of tool %s: %w", t.Name, err)
+			}
+			password, err := renderEnvTemplate("registry_password", t.RegistryPassword)
+			if err != nil {
+				return nil, fmt.Errorf("invalid registry_password of tool %s: %w", t.Name, err)
+			}
+			opts = append(opts, WithRegistryCredentials(username, password))
+		}
+		o, err := NewOCIInstaller(t.OCIRef, ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+		installers = append(installers, o)
+	}
+	if t.GitRepo != "" {
+		installers = append(installers, NewGoBuildInstaller(t.GitRepo, t.Binaries[0], t.GitSubFolder, ctx))
+	}
+	if len(installers) == 1 {
+		return installers[0], nil
+	}
+	return NewChainInstaller(installers...)
+}
+
+// transportOptions are the options safe to use with any host, netrc credentials are picked by
+// host already.
+func (t *ToolDefinition) transportOptions() []DownloadOption {
+	var opts []DownloadOption
+	if t.CABundle != "" {
+		opts = append(opts, WithCABundle(t.CABundle))
+	}
+	if t.Netrc != "" {
+		opts = append(opts, WithNetrc(t.Netrc))
+	}
+	return opts
+}
+
+// downloadOptions add the headers to transportOptions, for the download url template's host.
+func (t *ToolDefinition) downloadOptions() []DownloadOption {
+	opts := t.transportOptions()
+	for name, value := range t.Headers {
+		opts = append(opts, WithHeader(name, value))
+	}
+	return opts
+}
+
+func (t *ToolDefinition) validate() error {
+	if len(t.Binaries) == 0 {
+		return fmt.Errorf("tool %s has no binaries", t.Name)
+	}
+	if t.DownloadUrlTemplate == "" && t.GitHubRepo == "" && t.OCIRef == "" && t.GitRepo == "" {
+		return fmt.Errorf("tool %s needs a download_url_template, a github_repo, an oci_ref or a git_repo", t.Name)
+	}
+	if t.RegistryPassword != "" && t.RegistryUsername == "" {
+		return fmt.Errorf("tool %s has a registry_password without registry_username", t.Name)
+	}
+	if !validFileName(t.Name) || t.Name == RegistryDirName {
+		return fmt.Errorf("invalid tool name %q", t.Name)
+	}
+	for _, binary := range t.Binaries {
+		// A genv binary would replace genv itself when linked.
+		if !validFileName(binary) || binary == "genv" {
+			return fmt.Errorf("invalid binary name %q of tool %s", binary, t.Name)
+		}
+	}
+	return nil
+}
+
+// validFileName tells whether name can be used as a file name in a directory, tool names become
+// env dirs and binary names become files in the version dirs and shims.
+func validFileName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
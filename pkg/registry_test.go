@@ -0,0 +1,96 @@
+package pkg_test
+
+import (
+	"context"
+	"os"
+
+	"github.com/lonegunmanb/genv/pkg"
+)
+
+func (d *envSuite) TestLoadRegistry() {
+	d.files(map[string][]byte{
+		"/registry/vault.json":     []byte(`{"binaries":["vault"],"download_url_template":"https://releases.hashicorp.com/vault/{{ .Version }}/vault_{{ .Version }}_{{ .Os }}_{{ .Arch }}.zip","git_repo":"https://github.com/hashicorp/vault.git"}`),
+		"/registry/terraform.json": []byte(`{"binaries":["terraform"],"download_url_template":"https://releases.hashicorp.com/terraform/{{ .Version }}/terraform_{{ .Version }}_{{ .Os }}_{{ .Arch }}.zip"}`),
+		"/registry/kube.json":      []byte(`{"name":"kubectl","binaries":["kubectl","kubectl-convert"],"git_repo":"https://github.com/kubernetes/kubectl.git"}`),
+		"/registry/gh.json":        []byte(`{"binaries":["gh"],"github_repo":"cli/cli","asset_pattern":"_{{ .Os }}_{{ .Arch }}\\.tar\\.gz$","checksum_asset":"_checksums\\.txt$"}`),
+		"/registry/oras.json":      []byte(`{"binaries":["oras"],"oci_ref":"ghcr.io/team/oras:{{ .Version }}","git_repo":"https://github.com/oras-project/oras.git"}`),
+		"/registry/readme.md":      []byte(`not a tool`),
+	})
+	sut, err := pkg.LoadRegistry("/registry")
+	d.Require().NoError(err)
+	d.Equal([]string{"gh", "kubectl", "oras", "terraform", "vault"}, sut.Tools())
+
+	tool, ok := sut.ToolForBinary("kubectl-convert")
+	d.True(ok)
+	d.Equal("kubectl", tool.Name)
+	_, ok = sut.ToolForBinary("consul")
+	d.False(ok)
+
+	env, err := sut.Env("vault", "/tmp", context.Background())
+	d.Require().NoError(err)
+	d.Equal("vault", env.Name())
+	d.Equal("vault", env.BinaryName())
+	_, isLister := env.Installer.(pkg.RemoteLister)
+	d.True(isLister, "download with git repo falls back to go build")
+
+	env, err = sut.Env("terraform", "/tmp", context.Background())
+	d.Require().NoError(err)
+	d.IsType(&pkg.DownloadInstaller{}, env.Installer)
+
+	env, err = sut.Env("gh", "/tmp", context.Background())
+	d.Require().NoError(err)
+	d.IsType(&pkg.GitHubReleaseInstaller{}, env.Installer)
+
+	env, err = sut.Env("oras", "/tmp", context.Background())
+	d.Require().NoError(err)
+	_, isLister = env.Installer.(pkg.RemoteLister)
+	d.True(isLister, "the OCI registry falls back to go build")
+
+	env, err = sut.Env("kubectl", "/tmp", context.Background())
+	d.Require().NoError(err)
+	d.Equal([]string{"kubectl", "kubectl-convert"}, env.BinaryNames())
+	d.IsType(&pkg.GoBuildInstaller{}, env.Installer)
+
+	_, err = sut.Env("consul", "/tmp", context.Background())
+	d.Error(err)
+}
+
+func (d *envSuite) TestLoadRegistry_InvalidDefinition() {
+	cases := map[string]string{
+		"invalid_json":    `{"binaries":`,
+		"no_binaries":     `{"git_repo":"https://github.com/hashicorp/vault.git"}`,
+		"no_source":       `{"binaries":["vault"]}`,
+		"invalid_name":    `{"name":"../vault","binaries":["vault"],"git_repo":"https://github.com/hashicorp/vault.git"}`,
+		"reserved_name":   `{"name":"registry","binaries":["vault"],"git_repo":"https://github.com/hashicorp/vault.git"}`,
+		"binary_path":     `{"binaries":["bin/vault"],"git_repo":"https://github.com/hashicorp/vault.git"}`,
+		"binary_dot_dot":  `{"binaries":["vault",".."],"git_repo":"https://github.com/hashicorp/vault.git"}`,
+		"binary_empty":    `{"binaries":[""],"git_repo":"https://github.com/hashicorp/vault.git"}`,
+		"binary_genv":     `{"binaries":["genv"],"git_repo":"https://github.com/hashicorp/vault.git"}`,
+		"invalid_url_tpl": `{"binaries":["vault"],"download_url_template":"https://example.com/{{ .Unknown }}"}`,
+		"invalid_header":  `{"binaries":["vault"],"download_url_template":"https://example.com/{{ .Version }}","headers":{"Authorization":"{{ env"}}`,
+		"invalid_repo":    `{"binaries":["vault"],"github_repo":"hashicorp"}`,
+		"invalid_oci_ref": `{"binaries":["vault"],"oci_ref":"ghcr.io/hashicorp/vault"}`,
+		"password_only":   `{"binaries":["vault"],"oci_ref":"ghcr.io/hashicorp/vault:{{ .Version }}","registry_password":"s3cret"}`,
+		"invalid_secret":  `{"binaries":["vault"],"oci_ref":"ghcr.io/hashicorp/vault:{{ .Version }}","registry_username":"robot","registry_password":"{{ env"}`,
+		"missing_ca":      `{"binaries":["vault"],"download_url_template":"https://example.com/{{ .Version }}","ca_bundle":"/missing.pem"}`,
+	}
+	for desc, definition := range cases {
+		d.Run(desc, func() {
+			d.files(map[string][]byte{
+				"/registry/vault.json": []byte(definition),
+			})
+			sut, err := pkg.LoadRegistry("/registry")
+			if err == nil {
+				_, err = sut.Env("vault", "/tmp", context.Background())
+			}
+			d.Error(err)
+		})
+	}
+}
+
+func (d *envSuite) TestLoadRegistry_EmptyDir() {
+	d.Require().NoError(d.mockFs.MkdirAll("/registry", os.ModePerm))
+	sut, err := pkg.LoadRegistry("/registry")
+	d.NoError(err)
+	d.Empty(sut.Tools())
+}
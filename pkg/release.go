@@ -0,0 +1,43 @@
+package pkg
+
+import (
+	"fmt"
+)
+
+// ReleaseManifestName is the file that describes a release of a generated env, it's published
+// next to the archives and their SHA256SUMS.
+const ReleaseManifestName = "manifest.json"
+
+// ReleaseManifest lists the archives of one release of a generated env, one per platform. Every
+// archive holds the control plane and its shims.
+type ReleaseManifest struct {
+	Name      string            `json:"name"`
+	Version   string            `json:"version"`
+	Artifacts []ReleaseArtifact `json:"artifacts"`
+}
+
+type ReleaseArtifact struct {
+	Os     string `json:"os"`
+	Arch   string `json:"arch"`
+	File   string `json:"file"`
+	Sha256 string `json:"sha256"`
+}
+
+// Artifact returns the archive built for the given platform, nil if the release doesn't have one.
+func (m *ReleaseManifest) Artifact(os, arch string) *ReleaseArtifact {
+	for i := range m.Artifacts {
+		if m.Artifacts[i].Os == os && m.Artifacts[i].Arch == arch {
+			return &m.Artifacts[i]
+		}
+	}
+	return nil
+}
+
+// ReleaseArchiveName names the archive of a platform, zip on windows and tar.gz elsewhere.
+func ReleaseArchiveName(name, version, os, arch string) string {
+	ext := "tar.gz"
+	if os == "windows" {
+		ext = "zip"
+	}
+	return fmt.Sprintf("%s_%s_%s_%s.%s", name, version, os, arch, ext)
+}
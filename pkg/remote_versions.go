@@ -0,0 +1,76 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+var ErrNoVersionSource = errors.New("no remote version source configured")
+
+// RemoteVersionsCacheTTL is how long ListRemote trusts its cached result.
+var RemoteVersionsCacheTTL = time.Hour
+
+// RemoteLister is implemented by installers that can list the versions available for install.
+type RemoteLister interface {
+	ListRemote() ([]string, error)
+}
+
+type remoteVersionsCache struct {
+	FetchedAt time.Time `json:"fetched_at"`
+	Versions  []string  `json:"versions"`
+}
+
+// ListRemote returns the versions available for install sorted by SortVersions, the result is
+// cached for RemoteVersionsCacheTTL so shell completion stays fast. It returns ErrNoVersionSource
+// when the installer can't list versions.
+func (env *Env) ListRemote() ([]string, error) {
+	lister, ok := env.Installer.(RemoteLister)
+	if !ok {
+		return nil, ErrNoVersionSource
+	}
+	if cache, err := env.remoteVersionsCache(); err == nil && time.Since(cache.FetchedAt) < RemoteVersionsCacheTTL {
+		return cache.Versions, nil
+	}
+	versions, err := lister.ListRemote()
+	if err != nil {
+		return nil, err
+	}
+	SortVersions(versions)
+	content, err := json.Marshal(remoteVersionsCache{
+		FetchedAt: time.Now(),
+		Versions:  versions,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// A cache that can't be written only costs speed.
+	_ = writeFileAtomic(env.remoteVersionsCachePath(), content, 0644)
+	return versions, nil
+}
+
+func (env *Env) remoteVersionsCache() (*remoteVersionsCache, error) {
+	content, err := afero.ReadFile(Fs, env.remoteVersionsCachePath())
+	if err != nil {
+		return nil, err
+	}
+	var cache remoteVersionsCache
+	if err = json.Unmarshal(content, &cache); err != nil {
+		return nil, err
+	}
+	return &cache, nil
+}
+
+func (env *Env) remoteVersionsCachePath() string {
+	return filepath.Join(env.cacheDir(), "remote-versions.json")
+}
+
+func (env *Env) cacheDir() string {
+	if env.cachePath != "" {
+		return env.cachePath
+	}
+	return filepath.Join(env.homeDir, env.name, ".cache")
+}
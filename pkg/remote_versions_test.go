@@ -0,0 +1,64 @@
+package pkg_test
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lonegunmanb/genv/pkg"
+)
+
+type listerInstaller struct {
+	*MockInstaller
+	*MockRemoteLister
+}
+
+func (d *envSuite) TestListRemote_ShouldCache() {
+	lister := NewMockRemoteLister(d.mockCtrl)
+	lister.EXPECT().ListRemote().Return([]string{"v1.10.0", "v1.2.0"}, nil).Times(1)
+	sut := pkg.NewEnv("/tmp", "tfenv", "terraform", listerInstaller{
+		MockInstaller:    d.mockInstaller.(*MockInstaller),
+		MockRemoteLister: lister,
+	})
+	for i := 0; i < 2; i++ {
+		versions, err := sut.ListRemote()
+		d.NoError(err)
+		d.Equal([]string{"v1.2.0", "v1.10.0"}, versions)
+	}
+}
+
+func (d *envSuite) TestListRemote_StaleCache() {
+	fetchedAt := time.Now().Add(-2 * pkg.RemoteVersionsCacheTTL).Format(time.RFC3339)
+	d.files(map[string][]byte{
+		"/tmp/tfenv/.cache/remote-versions.json": []byte(fmt.Sprintf(`{"fetched_at":"%s","versions":["v1.0.0"]}`, fetchedAt)),
+	})
+	lister := NewMockRemoteLister(d.mockCtrl)
+	lister.EXPECT().ListRemote().Return([]string{"v1.0.0", "v1.1.0"}, nil).Times(1)
+	sut := pkg.NewEnv("/tmp", "tfenv", "terraform", listerInstaller{
+		MockInstaller:    d.mockInstaller.(*MockInstaller),
+		MockRemoteLister: lister,
+	})
+	versions, err := sut.ListRemote()
+	d.NoError(err)
+	d.Equal([]string{"v1.0.0", "v1.1.0"}, versions)
+}
+
+func (d *envSuite) TestListRemote_NoVersionSource() {
+	sut := pkg.NewEnv("/tmp", "tfenv", "terraform", d.mockInstaller)
+	_, err := sut.ListRemote()
+	d.ErrorIs(err, pkg.ErrNoVersionSource)
+}
+
+func (d *envSuite) TestListRemote_CacheDirIsNotAVersion() {
+	d.installedVersions("v1.0.0")
+	lister := NewMockRemoteLister(d.mockCtrl)
+	lister.EXPECT().ListRemote().Return([]string{"v1.0.0"}, nil).Times(1)
+	sut := pkg.NewEnv("/tmp", "tfenv", "terraform", listerInstaller{
+		MockInstaller:    d.mockInstaller.(*MockInstaller),
+		MockRemoteLister: lister,
+	})
+	_, err := sut.ListRemote()
+	d.NoError(err)
+	installed, err := sut.ListInstalled()
+	d.NoError(err)
+	d.Equal([]string{"v1.0.0"}, installed)
+}
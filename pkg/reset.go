@@ -0,0 +1,95 @@
+package pkg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/afero"
+)
+
+// Reset wipes the env: every installed version, the profile, the cache and finally the lock
+// file and the env directory itself. The env can be used again afterwards, it starts empty.
+func (env *Env) Reset() error {
+	if err := env.lock(); err != nil {
+		return err
+	}
+	dir := filepath.Join(env.homeDir, env.name)
+	err := removeAllBut(dir, env.lockPath())
+	if err == nil {
+		err = Fs.RemoveAll(env.cacheDir())
+	}
+	if unlockErr := env.unlock(); err == nil {
+		err = unlockErr
+	}
+	if err != nil {
+		return err
+	}
+	// The lock is taken on the real file system, see lock.
+	if err = os.Remove(env.lockPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err = Fs.Remove(dir); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func removeAllBut(dir, keep string) error {
+	entries, err := afero.ReadDir(Fs, dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if path == keep {
+			continue
+		}
+		if err = Fs.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ShimMarker returns the text every generated shim of the env named name embeds, so RemoveShims
+// can tell the shims from other binaries of the same name. The name is quoted, the shims of
+// vaultenv don't carry the marker of vault.
+func ShimMarker(name string) string {
+	return fmt.Sprintf("genv shim of %q", name)
+}
+
+// RemoveShims removes the shims of the env's binaries from dir, usually $GOBIN, and returns the
+// paths it removed. Missing shims are skipped, so are files without the env's ShimMarker, like a
+// binary installed with go install, with a warning.
+func (env *Env) RemoveShims(dir string) ([]string, error) {
+	var removed []string
+	marker := []byte(ShimMarker(env.name))
+	for _, binaryName := range env.binaryNames {
+		if Os == "windows" {
+			binaryName += ".exe"
+		}
+		path := filepath.Join(dir, binaryName)
+		content, err := afero.ReadFile(Fs, path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return removed, err
+		}
+		if !bytes.Contains(content, marker) {
+			warnf("Kept %s, it isn't a shim of %s\n", path, env.name)
+			continue
+		}
+		if err = Fs.Remove(path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, path)
+	}
+	return removed, nil
+}
@@ -0,0 +1,76 @@
+package pkg_test
+
+import (
+	"github.com/lonegunmanb/genv/pkg"
+	"github.com/spf13/afero"
+)
+
+func (d *envSuite) TestReset() {
+	d.installedVersions("1.5.7", "1.6.0")
+	d.files(map[string][]byte{
+		"/tmp/tfenv/.profile.json":               []byte(`{"version":"1.6.0"}`),
+		"/tmp/tfenv/.cache/remote-versions.json": []byte(`{}`),
+		"/tmp/tfenv/1.6.0/.genv.json":            []byte(`{}`),
+		"/tmp/consulenv/1.0.0/consul":            []byte("fake"),
+		"/src/project/.terraform-version":        []byte("1.5.7\n"),
+	})
+	sut := pkg.NewEnv("/tmp", "tfenv", "terraform", nil)
+	d.NoError(sut.Reset())
+	exists, err := afero.Exists(d.mockFs, "/tmp/tfenv")
+	d.NoError(err)
+	d.False(exists)
+	for _, kept := range []string{"/tmp/consulenv/1.0.0/consul", "/src/project/.terraform-version"} {
+		exists, err = afero.Exists(d.mockFs, kept)
+		d.NoError(err)
+		d.True(exists, kept)
+	}
+	installed, err := sut.ListInstalled()
+	d.NoError(err)
+	d.Empty(installed)
+	current, err := sut.CurrentVersion()
+	d.NoError(err)
+	d.Nil(current)
+}
+
+func (d *envSuite) TestReset_EmptyEnv() {
+	sut := pkg.NewEnv("/tmp", "emptyenv", "terraform", nil)
+	d.NoError(sut.Reset())
+}
+
+func (d *envSuite) TestRemoveShims() {
+	d.files(map[string][]byte{
+		"/gobin/go":      []byte("shim: " + pkg.ShimMarker("goenv")),
+		"/gobin/gofmt":   []byte("shim: " + pkg.ShimMarker("goenv")),
+		"/gobin/godoc":   []byte("installed with go install"),
+		"/gobin/goenv":   []byte("control plane"),
+		"/gobin/kubectl": []byte("other"),
+	})
+	sut, err := pkg.NewMultiBinaryEnv("/tmp", "goenv", []string{"go", "gofmt", "godoc"}, nil)
+	d.Require().NoError(err)
+	removed, err := sut.RemoveShims("/gobin")
+	d.NoError(err)
+	d.Equal([]string{"/gobin/go", "/gobin/gofmt"}, removed)
+	for _, kept := range []string{"/gobin/godoc", "/gobin/goenv", "/gobin/kubectl"} {
+		exists, err := afero.Exists(d.mockFs, kept)
+		d.NoError(err)
+		d.True(exists, kept)
+	}
+}
+
+func (d *envSuite) TestRemoveShims_EnvNamesSharingPrefix() {
+	d.files(map[string][]byte{
+		"/gobin/vault": []byte("shim: " + pkg.ShimMarker("vaultenv")),
+	})
+	sut := pkg.NewEnv("/tmp", "vault", "vault", nil)
+	removed, err := sut.RemoveShims("/gobin")
+	d.NoError(err)
+	d.Empty(removed)
+	exists, err := afero.Exists(d.mockFs, "/gobin/vault")
+	d.NoError(err)
+	d.True(exists, "the shim of vaultenv isn't one of vault")
+
+	sut = pkg.NewEnv("/tmp", "vaultenv", "vault", nil)
+	removed, err = sut.RemoveShims("/gobin")
+	d.NoError(err)
+	d.Equal([]string{"/gobin/vault"}, removed)
+}
@@ -0,0 +1,297 @@
+package pkg
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/spf13/afero"
+)
+
+// LatestRelease is the version the release url template is rendered with to find the newest release.
+const LatestRelease = "latest"
+
+// SelfUpdater replaces a generated control plane and its shims with a newer release built by
+// generate --release. The release url template points to the directory holding a release's
+// manifest and archives, {{ .Version }} is rendered as "latest" unless a version is asked for.
+type SelfUpdater struct {
+	releaseUrlTemplate string
+	name               string
+	currentVersion     string
+	binaryNames        []string
+	opts               httpOptions
+	client             *http.Client
+
+	ctx context.Context
+}
+
+// NewSelfUpdater creates an updater for the releases at releaseUrlTemplate. The download options,
+// like a CA bundle, netrc credentials or timeouts, apply to the manifest and archive downloads.
+func NewSelfUpdater(releaseUrlTemplate, name, currentVersion string, binaryNames []string, ctx context.Context, opts ...DownloadOption) (*SelfUpdater, error) {
+	if ctx == nil {
+		ctx = context.TODO()
+	}
+	if _, err := template.New("release").Parse(releaseUrlTemplate); err != nil {
+		return nil, err
+	}
+	u := &SelfUpdater{
+		releaseUrlTemplate: releaseUrlTemplate,
+		name:               name,
+		currentVersion:     currentVersion,
+		binaryNames:        binaryNames,
+		opts:               defaultHttpOptions(),
+		ctx:                ctx,
+	}
+	for _, opt := range opts {
+		opt(&u.opts)
+	}
+	if err := u.opts.validate(); err != nil {
+		return nil, err
+	}
+	client, err := u.opts.client()
+	if err != nil {
+		return nil, err
+	}
+	u.client = client
+	return u, nil
+}
+
+// ReleaseUrl returns the directory url of the given release.
+func (u *SelfUpdater) ReleaseUrl(version string) (string, error) {
+	tplt, err := template.New("release").Parse(u.releaseUrlTemplate)
+	if err != nil {
+		return "", err
+	}
+	var buff bytes.Buffer
+	if err = tplt.Execute(&buff, struct{ Version string }{Version: version}); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buff.String(), "/"), nil
+}
+
+// Check fetches the manifest of the given release, the latest one when version is empty, and
+// reports whether it's newer than the running version. A running version that isn't semver,
+// like a dev build, is always considered outdated.
+func (u *SelfUpdater) Check(version string) (*ReleaseManifest, bool, error) {
+	if version == "" {
+		version = LatestRelease
+	}
+	releaseUrl, err := u.ReleaseUrl(version)
+	if err != nil {
+		return nil, false, err
+	}
+	content, err := u.get(releaseUrl + "/" + ReleaseManifestName)
+	if err != nil {
+		return nil, false, err
+	}
+	var manifest ReleaseManifest
+	if err = json.Unmarshal(content, &manifest); err != nil {
+		return nil, false, fmt.Errorf("invalid release manifest %s: %w", releaseUrl, err)
+	}
+	latest, err := semver.NewVersion(manifest.Version)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid release version %s: %w", manifest.Version, err)
+	}
+	current, err := semver.NewVersion(u.currentVersion)
+	if err != nil {
+		return &manifest, true, nil
+	}
+	return &manifest, latest.GreaterThan(current), nil
+}
+
+// Update replaces executable, the running control plane, and the shims next to it with the given
+// release, the latest one when version is empty. The archive's checksum is verified and every
+// binary is staged before the first one is replaced. It returns the version it updated to, an
+// empty string when the running version is up to date. An explicit version is installed even
+// when it's older, to allow rollbacks.
+func (u *SelfUpdater) Update(executable, version string) (string, error) {
+	manifest, newer, err := u.Check(version)
+	if err != nil {
+		return "", err
+	}
+	if version == "" && !newer {
+		return "", nil
+	}
+	artifact := manifest.Artifact(Os, runtime.GOARCH)
+	if artifact == nil {
+		return "", fmt.Errorf("release %s has no build for %s/%s", manifest.Version, Os, runtime.GOARCH)
+	}
+	if version == "" {
+		version = LatestRelease
+	}
+	releaseUrl, err := u.ReleaseUrl(version)
+	if err != nil {
+		return "", err
+	}
+	archive, err := u.get(releaseUrl + "/" + artifact.File)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(archive)
+	if hex.EncodeToString(sum[:]) != strings.ToLower(artifact.Sha256) {
+		return "", fmt.Errorf("checksum mismatch for %s, expected %s, got %s", artifact.File, artifact.Sha256, hex.EncodeToString(sum[:]))
+	}
+	binaries, err := extractBinaries(artifact.File, archive, u.binaryFileNames())
+	if err != nil {
+		return "", err
+	}
+	if _, ok := binaries[u.binaryFileName(u.name)]; !ok {
+		return "", fmt.Errorf("%s doesn't contain %s", artifact.File, u.binaryFileName(u.name))
+	}
+	return manifest.Version, u.replace(executable, binaries)
+}
+
+// replace stages every binary next to its target, then renames them over the targets. Shims that
+// aren't installed next to the control plane are left alone.
+func (u *SelfUpdater) replace(executable string, binaries map[string][]byte) error {
+	dir := filepath.Dir(executable)
+	targets := map[string]string{u.binaryFileName(u.name): executable}
+	for _, binaryName := range u.binaryNames {
+		fileName := u.binaryFileName(binaryName)
+		target := filepath.Join(dir, fileName)
+		exists, err := afero.Exists(Fs, target)
+		if err != nil {
+			return err
+		}
+		if _, ok := binaries[fileName]; ok && exists {
+			targets[fileName] = target
+		}
+	}
+	staged := make(map[string]string)
+	defer func() {
+		for _, tmp := range staged {
+			_ = Fs.Remove(tmp)
+		}
+	}()
+	for fileName, target := range targets {
+		tmp := fmt.Sprintf("%s.%s.new", target, randStr(8))
+		if err := afero.WriteFile(Fs, tmp, binaries[fileName], 0755); err != nil {
+			return err
+		}
+		staged[target] = tmp
+		if err := Fs.Chmod(tmp, 0755); err != nil {
+			return err
+		}
+	}
+	for target, tmp := range staged {
+		if Os == "windows" {
+			// A running executable can't be overwritten on windows, but it can be renamed.
+			old := target + ".old"
+			_ = Fs.Remove(old)
+			if err := Fs.Rename(target, old); err != nil {
+				return err
+			}
+		}
+		if err := Fs.Rename(tmp, target); err != nil {
+			return err
+		}
+		delete(staged, target)
+	}
+	return nil
+}
+
+func (u *SelfUpdater) binaryFileNames() []string {
+	names := []string{u.binaryFileName(u.name)}
+	for _, binaryName := range u.binaryNames {
+		names = append(names, u.binaryFileName(binaryName))
+	}
+	return names
+}
+
+func (u *SelfUpdater) binaryFileName(name string) string {
+	if Os == "windows" {
+		return name + ".exe"
+	}
+	return name
+}
+
+func (u *SelfUpdater) get(url string) ([]byte, error) {
+	ctx := u.ctx
+	if u.opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, u.opts.timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if req.Header, err = u.opts.header(url); err != nil {
+		return nil, err
+	}
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// extractBinaries returns the content of the wanted files found in a tar.gz or zip archive.
+func extractBinaries(archiveName string, archive []byte, wanted []string) (map[string][]byte, error) {
+	binaries := make(map[string][]byte)
+	if strings.HasSuffix(archiveName, ".zip") {
+		zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range zr.File {
+			if !slices.Contains(wanted, f.Name) {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			content, err := io.ReadAll(rc)
+			_ = rc.Close()
+			if err != nil {
+				return nil, err
+			}
+			binaries[f.Name] = content
+		}
+		return binaries, nil
+	}
+	gr, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gr)
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return binaries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if h.Typeflag != tar.TypeReg || !slices.Contains(wanted, h.Name) {
+			continue
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		binaries[h.Name] = content
+	}
+}
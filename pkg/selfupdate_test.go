@@ -0,0 +1,248 @@
+package pkg_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"github.com/lonegunmanb/genv/pkg"
+	"github.com/prashantv/gostub"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/suite"
+)
+
+type selfUpdateSuite struct {
+	suite.Suite
+	stub     *gostub.Stubs
+	mockFs   afero.Fs
+	server   *httptest.Server
+	releases map[string][]byte
+}
+
+func TestSelfUpdater(t *testing.T) {
+	suite.Run(t, new(selfUpdateSuite))
+}
+
+func (s *selfUpdateSuite) SetupTest() {
+	s.mockFs = afero.NewMemMapFs()
+	s.stub = gostub.Stub(&pkg.Fs, s.mockFs).
+		Stub(&pkg.Os, "linux")
+	s.releases = make(map[string][]byte)
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := s.releases[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(content)
+	}))
+	s.Require().NoError(afero.WriteFile(s.mockFs, "/bin/vaultenv", []byte("old vaultenv"), 0755))
+	s.Require().NoError(afero.WriteFile(s.mockFs, "/bin/vault", []byte("old vault"), 0755))
+}
+
+func (s *selfUpdateSuite) TearDownTest() {
+	s.server.Close()
+	s.stub.Reset()
+}
+
+// publish serves a release under /<dir>/, the archive is built for the running arch.
+func (s *selfUpdateSuite) publish(dir, version string, files map[string]string, zipped bool) {
+	var archive []byte
+	file := pkg.ReleaseArchiveName("vaultenv", version, "linux", runtime.GOARCH)
+	if zipped {
+		archive = zipArchive(s.T(), files)
+		file = pkg.ReleaseArchiveName("vaultenv", version, "windows", runtime.GOARCH)
+	} else {
+		archive = tarGzArchive(s.T(), files)
+	}
+	sum := sha256.Sum256(archive)
+	goos := "linux"
+	if zipped {
+		goos = "windows"
+	}
+	manifest, err := json.Marshal(pkg.ReleaseManifest{
+		Name:    "vaultenv",
+		Version: version,
+		Artifacts: []pkg.ReleaseArtifact{
+			{Os: goos, Arch: runtime.GOARCH, File: file, Sha256: hex.EncodeToString(sum[:])},
+		},
+	})
+	s.Require().NoError(err)
+	s.releases["/"+dir+"/"+pkg.ReleaseManifestName] = manifest
+	s.releases["/"+dir+"/"+file] = archive
+}
+
+func (s *selfUpdateSuite) updater(currentVersion string) *pkg.SelfUpdater {
+	u, err := pkg.NewSelfUpdater(s.server.URL+"/{{ .Version }}/", "vaultenv", currentVersion, []string{"vault", "vault-helper"}, nil)
+	s.Require().NoError(err)
+	return u
+}
+
+func (s *selfUpdateSuite) content(path string) string {
+	content, err := afero.ReadFile(s.mockFs, path)
+	s.Require().NoError(err)
+	return string(content)
+}
+
+func (s *selfUpdateSuite) TestUpdateReplacesControlPlaneAndShims() {
+	s.publish("latest", "1.1.0", map[string]string{
+		"vaultenv":     "new vaultenv",
+		"vault":        "new vault",
+		"vault-helper": "new vault-helper",
+	}, false)
+	version, err := s.updater("1.0.0").Update("/bin/vaultenv", "")
+	s.NoError(err)
+	s.Equal("1.1.0", version)
+	s.Equal("new vaultenv", s.content("/bin/vaultenv"))
+	s.Equal("new vault", s.content("/bin/vault"))
+	// vault-helper isn't installed next to the control plane, it's not added.
+	exists, err := afero.Exists(s.mockFs, "/bin/vault-helper")
+	s.NoError(err)
+	s.False(exists)
+	entries, err := afero.ReadDir(s.mockFs, "/bin")
+	s.NoError(err)
+	s.Len(entries, 2, "staged files must be gone")
+}
+
+func (s *selfUpdateSuite) TestUpToDate() {
+	s.publish("latest", "1.0.0", map[string]string{"vaultenv": "new vaultenv"}, false)
+	manifest, newer, err := s.updater("1.0.0").Check("")
+	s.NoError(err)
+	s.False(newer)
+	s.Equal("1.0.0", manifest.Version)
+	version, err := s.updater("1.0.0").Update("/bin/vaultenv", "")
+	s.NoError(err)
+	s.Equal("", version)
+	s.Equal("old vaultenv", s.content("/bin/vaultenv"))
+}
+
+func (s *selfUpdateSuite) TestDevBuildIsAlwaysOutdated() {
+	s.publish("latest", "1.0.0", map[string]string{"vaultenv": "new vaultenv"}, false)
+	_, newer, err := s.updater("dev").Check("")
+	s.NoError(err)
+	s.True(newer)
+}
+
+func (s *selfUpdateSuite) TestExplicitVersionAllowsRollback() {
+	s.publish("0.9.0", "0.9.0", map[string]string{"vaultenv": "older vaultenv", "vault": "older vault"}, false)
+	version, err := s.updater("1.0.0").Update("/bin/vaultenv", "0.9.0")
+	s.NoError(err)
+	s.Equal("0.9.0", version)
+	s.Equal("older vaultenv", s.content("/bin/vaultenv"))
+	s.Equal("older vault", s.content("/bin/vault"))
+}
+
+func (s *selfUpdateSuite) TestChecksumMismatchLeavesBinariesUntouched() {
+	s.publish("latest", "1.1.0", map[string]string{"vaultenv": "new vaultenv", "vault": "new vault"}, false)
+	file := pkg.ReleaseArchiveName("vaultenv", "1.1.0", "linux", runtime.GOARCH)
+	s.releases["/latest/"+file] = tarGzArchive(s.T(), map[string]string{"vaultenv": "tampered"})
+	_, err := s.updater("1.0.0").Update("/bin/vaultenv", "")
+	s.Error(err)
+	s.Contains(err.Error(), "checksum mismatch")
+	s.Equal("old vaultenv", s.content("/bin/vaultenv"))
+	s.Equal("old vault", s.content("/bin/vault"))
+}
+
+func (s *selfUpdateSuite) TestMissingPlatform() {
+	s.publish("latest", "1.1.0", map[string]string{"vaultenv.exe": "new vaultenv"}, true)
+	_, err := s.updater("1.0.0").Update("/bin/vaultenv", "")
+	s.Error(err)
+	s.Contains(err.Error(), "no build for linux")
+}
+
+func (s *selfUpdateSuite) TestWindowsZip() {
+	s.stub.Stub(&pkg.Os, "windows")
+	s.Require().NoError(afero.WriteFile(s.mockFs, "/bin/vaultenv.exe", []byte("old vaultenv"), 0755))
+	s.publish("latest", "1.1.0", map[string]string{"vaultenv.exe": "new vaultenv"}, true)
+	version, err := s.updater("1.0.0").Update("/bin/vaultenv.exe", "")
+	s.NoError(err)
+	s.Equal("1.1.0", version)
+	s.Equal("new vaultenv", s.content("/bin/vaultenv.exe"))
+	s.Equal("old vaultenv", s.content("/bin/vaultenv.exe.old"))
+}
+
+func (s *selfUpdateSuite) TestManifestNotFound() {
+	_, _, err := s.updater("1.0.0").Check("")
+	s.Error(err)
+	s.Contains(err.Error(), "404")
+}
+
+func (s *selfUpdateSuite) TestDownloadOptions() {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "deployer" || password != "pa55" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		content, ok := s.releases[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(content)
+	}))
+	defer server.Close()
+	s.publish("latest", "1.1.0", map[string]string{"vaultenv": "new vaultenv"}, false)
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	s.Require().NoError(afero.WriteFile(s.mockFs, "/etc/ca.pem", ca, 0600))
+	s.Require().NoError(afero.WriteFile(s.mockFs, "/etc/netrc", []byte("machine 127.0.0.1 login deployer password pa55\n"), 0600))
+
+	u, err := pkg.NewSelfUpdater(server.URL+"/{{ .Version }}/", "vaultenv", "1.0.0", nil, nil)
+	s.Require().NoError(err)
+	_, _, err = u.Check("")
+	s.Error(err, "the server's CA isn't trusted without the bundle")
+
+	u, err = pkg.NewSelfUpdater(server.URL+"/{{ .Version }}/", "vaultenv", "1.0.0", nil, nil, pkg.WithCABundle("/etc/ca.pem"), pkg.WithNetrc("/etc/netrc"))
+	s.Require().NoError(err)
+	version, err := u.Update("/bin/vaultenv", "")
+	s.NoError(err)
+	s.Equal("1.1.0", version)
+	s.Equal("new vaultenv", s.content("/bin/vaultenv"))
+}
+
+func tarGzArchive(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,131 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+type ShellInitOptions struct {
+	// Name is the control plane binary name.
+	Name string
+	// BinDir is the directory that contains the shim, it's put on PATH.
+	BinDir string
+	// CdHook installs the pinned version whenever the shell enters a directory.
+	CdHook bool
+}
+
+var SupportedShells = []string{"bash", "zsh", "fish", "powershell"}
+
+var shellInitTemplates = map[string]string{
+	"bash": `# {{ .Name }} shell integration, add 'eval "$({{ .Name }} init bash)"' to ~/.bashrc
+case ":${PATH}:" in
+  *:{{ quote .BinDir }}:*) ;;
+  *) export PATH={{ quote .BinDir }}":${PATH}" ;;
+esac
+source <({{ .Name }} completion bash)
+{{- if .CdHook }}
+_{{ .FuncName }}_hook() {
+  if [ "${_{{ .FuncName }}_last_dir}" != "${PWD}" ]; then
+    _{{ .FuncName }}_last_dir="${PWD}"
+    command {{ .Name }} install --pinned >/dev/null
+  fi
+}
+case ";${PROMPT_COMMAND:-};" in
+  *";_{{ .FuncName }}_hook;"*) ;;
+  *) PROMPT_COMMAND="_{{ .FuncName }}_hook${PROMPT_COMMAND:+;${PROMPT_COMMAND}}" ;;
+esac
+{{- end }}
+`,
+	"zsh": `# {{ .Name }} shell integration, add 'eval "$({{ .Name }} init zsh)"' to ~/.zshrc
+case ":${PATH}:" in
+  *:{{ quote .BinDir }}:*) ;;
+  *) export PATH={{ quote .BinDir }}":${PATH}" ;;
+esac
+(( $+functions[compdef] )) || { autoload -Uz compinit && compinit }
+source <({{ .Name }} completion zsh)
+{{- if .CdHook }}
+_{{ .FuncName }}_hook() {
+  command {{ .Name }} install --pinned >/dev/null
+}
+autoload -Uz add-zsh-hook
+add-zsh-hook chpwd _{{ .FuncName }}_hook
+_{{ .FuncName }}_hook
+{{- end }}
+`,
+	"fish": `# {{ .Name }} shell integration, add '{{ .Name }} init fish | source' to ~/.config/fish/config.fish
+if not contains -- {{ quote .BinDir }} $PATH
+    set -gx PATH {{ quote .BinDir }} $PATH
+end
+{{ .Name }} completion fish | source
+{{- if .CdHook }}
+function _{{ .FuncName }}_hook --on-variable PWD
+    command {{ .Name }} install --pinned >/dev/null
+end
+_{{ .FuncName }}_hook
+{{- end }}
+`,
+	"powershell": `# {{ .Name }} shell integration, add '{{ .Name }} init powershell | Out-String | Invoke-Expression' to $PROFILE
+if (-not (($env:PATH -split [IO.Path]::PathSeparator) -contains {{ quote .BinDir }})) {
+    $env:PATH = {{ quote .BinDir }} + [IO.Path]::PathSeparator + $env:PATH
+}
+{{ .Name }} completion powershell | Out-String | Invoke-Expression
+{{- if .CdHook }}
+$global:_{{ .FuncName }}_last_dir = $null
+$global:_{{ .FuncName }}_prompt = $function:prompt
+function global:prompt {
+    if ($global:_{{ .FuncName }}_last_dir -ne $PWD.Path) {
+        $global:_{{ .FuncName }}_last_dir = $PWD.Path
+        & {{ quote .Name }} install --pinned | Out-Null
+    }
+    & $global:_{{ .FuncName }}_prompt
+}
+{{- end }}
+`,
+}
+
+var shellQuotes = map[string]func(string) string{
+	"bash": posixQuote,
+	"zsh":  posixQuote,
+	"fish": func(s string) string {
+		return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+	},
+	"powershell": func(s string) string {
+		return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	},
+}
+
+var nonIdentifier = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// ShellInit returns the snippet that wires the shim directory, completion and, optionally, the
+// cd hook into the given shell.
+func ShellInit(shell string, opts ShellInitOptions) (string, error) {
+	tpl, ok := shellInitTemplates[shell]
+	if !ok {
+		return "", fmt.Errorf("unsupported shell %s, supported shells are: %s", shell, strings.Join(SupportedShells, ", "))
+	}
+	t, err := template.New(shell).Funcs(template.FuncMap{
+		"quote": shellQuotes[shell],
+	}).Parse(tpl)
+	if err != nil {
+		return "", err
+	}
+	var buff bytes.Buffer
+	err = t.Execute(&buff, struct {
+		ShellInitOptions
+		FuncName string
+	}{
+		ShellInitOptions: opts,
+		FuncName:         nonIdentifier.ReplaceAllString(opts.Name, "_"),
+	})
+	if err != nil {
+		return "", err
+	}
+	return buff.String(), nil
+}
+
+func posixQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,63 @@
+package pkg_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lonegunmanb/genv/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestShellInit(t *testing.T) {
+	for _, shell := range pkg.SupportedShells {
+		for _, cdHook := range []bool{false, true} {
+			golden := shell
+			if cdHook {
+				golden += "_cd_hook"
+			}
+			t.Run(golden, func(t *testing.T) {
+				actual, err := pkg.ShellInit(shell, pkg.ShellInitOptions{
+					Name:   "vault-env",
+					BinDir: "/home/user/go/bin",
+					CdHook: cdHook,
+				})
+				require.NoError(t, err)
+				goldenPath := filepath.Join("testdata", "shell_init", golden+".golden")
+				if *update {
+					require.NoError(t, os.MkdirAll(filepath.Dir(goldenPath), 0755))
+					require.NoError(t, os.WriteFile(goldenPath, []byte(actual), 0644))
+				}
+				expected, err := os.ReadFile(goldenPath)
+				require.NoError(t, err)
+				assert.Equal(t, string(expected), actual)
+			})
+		}
+	}
+}
+
+func TestShellInit_QuoteBinDir(t *testing.T) {
+	cases := map[string]string{
+		"bash":       `'/home/o'\''brien/go bin'`,
+		"zsh":        `'/home/o'\''brien/go bin'`,
+		"fish":       `'/home/o\'brien/go bin'`,
+		"powershell": `'/home/o''brien/go bin'`,
+	}
+	for shell, quoted := range cases {
+		actual, err := pkg.ShellInit(shell, pkg.ShellInitOptions{
+			Name:   "vaultenv",
+			BinDir: "/home/o'brien/go bin",
+		})
+		require.NoError(t, err)
+		assert.Contains(t, actual, quoted, shell)
+	}
+}
+
+func TestShellInit_UnsupportedShell(t *testing.T) {
+	_, err := pkg.ShellInit("tcsh", pkg.ShellInitOptions{Name: "vaultenv"})
+	assert.Error(t, err)
+}
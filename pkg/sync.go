@@ -0,0 +1,96 @@
+package pkg
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SyncJob installs Version with Env, Tool names it in progress and error reports.
+type SyncJob struct {
+	Tool    string
+	Version string
+	Env     *Env
+}
+
+type SyncFailure struct {
+	Tool    string
+	Version string
+	Err     error
+}
+
+// SyncError lists every job that failed during Sync.
+type SyncError struct {
+	Failures []SyncFailure
+}
+
+func (e *SyncError) Error() string {
+	var sb strings.Builder
+	_, _ = fmt.Fprintf(&sb, "%d tool(s) failed to install:", len(e.Failures))
+	for _, f := range e.Failures {
+		_, _ = fmt.Fprintf(&sb, "\n  %s %s: %s", f.Tool, f.Version, f.Err.Error())
+	}
+	return sb.String()
+}
+
+// Sync installs the missing versions of all jobs with at most parallelism installs running at
+// once, progress goes to out. Every job runs even when others fail, the failures are reported
+// together as *SyncError.
+func Sync(jobs []SyncJob, parallelism int, out io.Writer) error {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	var (
+		mu       sync.Mutex
+		done     int
+		failures []SyncFailure
+		wg       sync.WaitGroup
+	)
+	report := func(job SyncJob, status string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		done++
+		_, _ = fmt.Fprintf(out, "[%d/%d] %s %s %s\n", done, len(jobs), job.Tool, job.Version, status)
+		if err != nil {
+			failures = append(failures, SyncFailure{
+				Tool:    job.Tool,
+				Version: job.Version,
+				Err:     err,
+			})
+		}
+	}
+	slots := make(chan struct{}, parallelism)
+	for _, job := range jobs {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(job SyncJob) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			installed, err := job.Env.Installed(job.Version)
+			if err == nil && installed {
+				report(job, "already installed", nil)
+				return
+			}
+			if err == nil {
+				err = job.Env.Install(job.Version)
+			}
+			if err != nil {
+				report(job, fmt.Sprintf("failed: %s", err.Error()), err)
+				return
+			}
+			report(job, "installed", nil)
+		}(job)
+	}
+	wg.Wait()
+	if len(failures) > 0 {
+		sort.Slice(failures, func(i, j int) bool {
+			return failures[i].Tool < failures[j].Tool
+		})
+		return &SyncError{Failures: failures}
+	}
+	return nil
+}
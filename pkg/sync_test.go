@@ -0,0 +1,83 @@
+package pkg_test
+
+import (
+	"bytes"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/lonegunmanb/genv/pkg"
+	"github.com/spf13/afero"
+	"go.uber.org/mock/gomock"
+)
+
+func (d *envSuite) TestSync() {
+	var running, maxRunning int32
+	install := func(version, dstPath string) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return afero.WriteFile(d.mockFs, dstPath, []byte("fake"), 0755)
+	}
+	d.installedVersions("1.5.7")
+	var jobs []pkg.SyncJob
+	for i, tool := range []string{"vault", "consul", "nomad", "packer"} {
+		installer := NewMockInstaller(d.mockCtrl)
+		installer.EXPECT().Install("1.0.0", gomock.Any()).DoAndReturn(install).Times(1)
+		jobs = append(jobs, pkg.SyncJob{
+			Tool:    tool,
+			Version: "1.0.0",
+			Env:     pkg.NewEnv("/tmp", fmt.Sprintf("synctest%d", i), tool, installer),
+		})
+	}
+	jobs = append(jobs, pkg.SyncJob{
+		Tool:    "terraform",
+		Version: "1.5.7",
+		Env:     pkg.NewEnv("/tmp", "tfenv", "terraform", NewMockInstaller(d.mockCtrl)),
+	})
+	var out bytes.Buffer
+	err := pkg.Sync(jobs, 2, &out)
+	d.NoError(err)
+	d.LessOrEqual(atomic.LoadInt32(&maxRunning), int32(2))
+	d.Contains(out.String(), "terraform 1.5.7 already installed")
+	d.Contains(out.String(), "[5/5]")
+	for _, job := range jobs {
+		installed, err := job.Env.Installed(job.Version)
+		d.NoError(err)
+		d.True(installed, job.Tool)
+	}
+}
+
+func (d *envSuite) TestSync_ReportAllFailures() {
+	var jobs []pkg.SyncJob
+	for i, tool := range []string{"vault", "consul", "nomad"} {
+		installer := NewMockInstaller(d.mockCtrl)
+		if tool == "consul" {
+			installer.EXPECT().Install("1.0.0", gomock.Any()).DoAndReturn(func(version, dstPath string) error {
+				return afero.WriteFile(d.mockFs, dstPath, []byte("fake"), 0755)
+			}).Times(1)
+		} else {
+			installer.EXPECT().Install("1.0.0", gomock.Any()).Return(fmt.Errorf("%s is not available", tool)).Times(1)
+		}
+		jobs = append(jobs, pkg.SyncJob{
+			Tool:    tool,
+			Version: "1.0.0",
+			Env:     pkg.NewEnv("/tmp", fmt.Sprintf("synctest%d", i), tool, installer),
+		})
+	}
+	var out bytes.Buffer
+	err := pkg.Sync(jobs, 4, &out)
+	var syncErr *pkg.SyncError
+	d.Require().ErrorAs(err, &syncErr)
+	d.Len(syncErr.Failures, 2)
+	d.Equal("nomad", syncErr.Failures[0].Tool)
+	d.Equal("vault", syncErr.Failures[1].Tool)
+	d.Contains(err.Error(), "vault is not available")
+	d.Contains(err.Error(), "nomad is not available")
+}
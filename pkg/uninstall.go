@@ -0,0 +1,139 @@
+package pkg
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// UninstallCheck tells what removing a version would break.
+type UninstallCheck struct {
+	Version string
+	// PinnedBy is the version file or .tool-versions that pins the version for the directory
+	// checked, empty when it's not pinned there.
+	PinnedBy string
+	// Active is true when the version is the one selected by use, the shim falls back to the
+	// default version once it's gone.
+	Active bool
+}
+
+// CheckUninstall reports whether version is pinned for dir or is the active version.
+func (env *Env) CheckUninstall(version, dir string) (*UninstallCheck, error) {
+	check := &UninstallCheck{Version: version}
+	pinned, source, err := env.pinnedVersion(dir)
+	if err != nil {
+		return nil, err
+	}
+	if pinned == version {
+		check.PinnedBy = source
+	}
+	current, err := env.CurrentVersion()
+	if err != nil {
+		return nil, err
+	}
+	check.Active = current != nil && *current == version
+	return check, nil
+}
+
+// MatchInstalled expands every argument, an installed version or a semver range like "<1.5" or
+// "1.4.x", to the installed versions it designates. The result is sorted and free of
+// duplicates, an argument that matches nothing is an error.
+func (env *Env) MatchInstalled(args []string) ([]string, error) {
+	installed, err := env.ListInstalled()
+	if err != nil {
+		return nil, err
+	}
+	var matched []string
+	for _, arg := range args {
+		if slices.Contains(installed, arg) {
+			matched = append(matched, arg)
+			continue
+		}
+		// A bare 1.6 is a version someone thinks is installed, not the 1.6.x range semver reads.
+		if !isVersionRange(arg) {
+			return nil, fmt.Errorf("version %s is not installed", arg)
+		}
+		if _, err := semver.NewConstraint(arg); err != nil {
+			return nil, fmt.Errorf("version %s is not installed", arg)
+		}
+		versions, err := FilterVersions(installed, arg)
+		if err != nil {
+			return nil, err
+		}
+		if len(versions) == 0 {
+			return nil, fmt.Errorf("no installed version matches %s", arg)
+		}
+		matched = append(matched, versions...)
+	}
+	SortVersions(matched)
+	return slices.Compact(matched), nil
+}
+
+// isVersionRange tells whether arg is written as a range, with an operator, a hyphen range or an
+// x or * wildcard.
+func isVersionRange(arg string) bool {
+	if strings.ContainsAny(arg, "<>=!~^*,|") || strings.Contains(arg, " - ") {
+		return true
+	}
+	for _, part := range strings.Split(arg, ".") {
+		if part == "x" || part == "X" {
+			return true
+		}
+	}
+	return false
+}
+
+// UninstallOptions guards UninstallMatching.
+type UninstallOptions struct {
+	// Dir is the directory whose pinned version must not be removed, usually the working directory.
+	Dir string
+	// Force uninstalls pinned and active versions without asking.
+	Force bool
+	// In and Out are used to confirm the removal of the active version.
+	In  io.Reader
+	Out io.Writer
+}
+
+// UninstallMatching uninstalls the versions designated by args, see MatchInstalled. It refuses
+// to remove a version pinned for opts.Dir and asks before removing the active version, unless
+// opts.Force is set. Nothing is removed when a version is refused.
+func (env *Env) UninstallMatching(args []string, opts UninstallOptions) error {
+	versions, err := env.MatchInstalled(args)
+	if err != nil {
+		return err
+	}
+	var checks []*UninstallCheck
+	for _, version := range versions {
+		check, err := env.CheckUninstall(version, opts.Dir)
+		if err != nil {
+			return err
+		}
+		if check.PinnedBy != "" {
+			_, _ = fmt.Fprintf(opts.Out, "Warning: version %s is pinned by %s\n", version, check.PinnedBy)
+			if !opts.Force {
+				return fmt.Errorf("refusing to uninstall version %s pinned by %s, use --force to uninstall it anyway", version, check.PinnedBy)
+			}
+		}
+		checks = append(checks, check)
+	}
+	for _, check := range checks {
+		if check.Active && !opts.Force {
+			ok, err := Confirm(opts.In, opts.Out, fmt.Sprintf("Version %s is the active version, uninstall it?", check.Version))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				_, _ = fmt.Fprintf(opts.Out, "Keeping version %s\n", check.Version)
+				continue
+			}
+		}
+		_, _ = fmt.Fprintf(opts.Out, "Uninstalling version: %s\n", check.Version)
+		if err = env.Uninstall(check.Version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package pkg_test
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/lonegunmanb/genv/pkg"
+)
+
+func (d *envSuite) TestCheckUninstall() {
+	d.installedVersions("1.5.7", "1.6.0")
+	d.files(map[string][]byte{
+		"/tmp/tfenv/.profile.json":        []byte(`{"version":"1.6.0"}`),
+		"/src/project/.terraform-version": []byte("1.5.7\n"),
+	})
+	sut := pkg.NewEnv("/tmp", "tfenv", "terraform", nil)
+
+	check, err := sut.CheckUninstall("1.5.7", "/src/project/module")
+	d.NoError(err)
+	d.Equal("/src/project/.terraform-version", check.PinnedBy)
+	d.False(check.Active)
+
+	check, err = sut.CheckUninstall("1.6.0", "/src/project")
+	d.NoError(err)
+	d.Equal("", check.PinnedBy)
+	d.True(check.Active)
+
+	check, err = sut.CheckUninstall("1.5.7", "/elsewhere")
+	d.NoError(err)
+	d.Equal(&pkg.UninstallCheck{Version: "1.5.7"}, check)
+}
+
+func (d *envSuite) TestMatchInstalled() {
+	d.installedVersions("1.4.0", "1.4.2", "1.5.7", "1.6.0", "nightly")
+	sut := pkg.NewEnv("/tmp", "tfenv", "terraform", nil)
+	cases := []struct {
+		desc     string
+		args     []string
+		expected []string
+	}{
+		{desc: "exact versions", args: []string{"1.6.0", "nightly"}, expected: []string{"1.6.0", "nightly"}},
+		{desc: "range", args: []string{"<1.5"}, expected: []string{"1.4.0", "1.4.2"}},
+		{desc: "overlapping ranges are deduplicated", args: []string{"1.4.x", "<1.6", "1.4.2"}, expected: []string{"1.4.0", "1.4.2", "1.5.7"}},
+		{desc: "wildcard", args: []string{"1.*"}, expected: []string{"1.4.0", "1.4.2", "1.5.7", "1.6.0"}},
+		{desc: "hyphen range", args: []string{"1.4.1 - 1.5.7"}, expected: []string{"1.4.2", "1.5.7"}},
+	}
+	for _, c := range cases {
+		d.Run(c.desc, func() {
+			d.installedVersions("1.4.0", "1.4.2", "1.5.7", "1.6.0", "nightly")
+			versions, err := sut.MatchInstalled(c.args)
+			d.NoError(err)
+			d.Equal(c.expected, versions)
+		})
+	}
+}
+
+func (d *envSuite) TestMatchInstalled_NoMatch() {
+	d.installedVersions("1.4.0")
+	sut := pkg.NewEnv("/tmp", "tfenv", "terraform", nil)
+	_, err := sut.MatchInstalled([]string{"1.4.0", "1.5.0"})
+	d.ErrorContains(err, "version 1.5.0 is not installed")
+	_, err = sut.MatchInstalled([]string{">=2"})
+	d.ErrorContains(err, "no installed version matches >=2")
+	_, err = sut.MatchInstalled([]string{"nightly"})
+	d.ErrorContains(err, "version nightly is not installed")
+	_, err = sut.MatchInstalled([]string{"1.4"})
+	d.ErrorContains(err, "version 1.4 is not installed", "a partial version isn't a range")
+}
+
+func (d *envSuite) TestUninstallMatching_RefusePinnedVersion() {
+	d.installedVersions("1.5.7", "1.6.0")
+	d.files(map[string][]byte{
+		"/src/project/.terraform-version": []byte("1.5.7\n"),
+	})
+	sut := pkg.NewEnv("/tmp", "tfenv", "terraform", nil)
+	var out bytes.Buffer
+	err := sut.UninstallMatching([]string{"<2"}, pkg.UninstallOptions{Dir: "/src/project", Out: &out})
+	d.ErrorContains(err, "refusing to uninstall version 1.5.7")
+	d.Contains(out.String(), "Warning: version 1.5.7 is pinned by /src/project/.terraform-version")
+	installed, err := sut.ListInstalled()
+	d.NoError(err)
+	d.Equal([]string{"1.5.7", "1.6.0"}, installed, "nothing is removed when a version is refused")
+
+	err = sut.UninstallMatching([]string{"<2"}, pkg.UninstallOptions{Dir: "/src/project", Force: true, Out: &out})
+	d.NoError(err)
+	installed, err = sut.ListInstalled()
+	d.NoError(err)
+	d.Empty(installed)
+}
+
+func (d *envSuite) TestUninstallMatching_ConfirmActiveVersion() {
+	cases := []struct {
+		desc      string
+		answer    string
+		force     bool
+		remaining []string
+	}{
+		{desc: "declined", answer: "n\n", remaining: []string{"1.6.0"}},
+		{desc: "confirmed", answer: "y\n"},
+		{desc: "no answer", remaining: []string{"1.6.0"}},
+		{desc: "forced", force: true},
+	}
+	for _, c := range cases {
+		d.Run(c.desc, func() {
+			d.installedVersions("1.5.7", "1.6.0")
+			d.files(map[string][]byte{
+				"/tmp/tfenv/.profile.json": []byte(`{"version":"1.6.0"}`),
+			})
+			sut := pkg.NewEnv("/tmp", "tfenv", "terraform", nil)
+			var out bytes.Buffer
+			err := sut.UninstallMatching([]string{"1.5.7", "1.6.0"}, pkg.UninstallOptions{
+				Dir:   "/src",
+				Force: c.force,
+				In:    strings.NewReader(c.answer),
+				Out:   &out,
+			})
+			d.NoError(err)
+			installed, err := sut.ListInstalled()
+			d.NoError(err)
+			d.Equal(c.remaining, installed)
+			d.Equal(!c.force, strings.Contains(out.String(), "Version 1.6.0 is the active version, uninstall it? [y/N]"))
+		})
+	}
+}
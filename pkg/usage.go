@@ -0,0 +1,74 @@
+package pkg
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// Usage is recorded by the shim in <version>/.usage, the file holds the invocation count and
+// its modification time is the last time the version was run.
+type Usage struct {
+	LastUsed time.Time
+	Count    int
+}
+
+// Usage returns how often and how recently the shim ran the given version.
+func (env *Env) Usage(version string) (*Usage, error) {
+	lastUsed, err := env.LastUsed(version)
+	if err != nil {
+		return nil, err
+	}
+	usage := &Usage{
+		LastUsed: lastUsed,
+	}
+	content, err := afero.ReadFile(Fs, env.usagePath(version))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return usage, nil
+		}
+		return nil, err
+	}
+	// Concurrent shims may race on the file, treat anything unreadable as unknown.
+	if count, err := strconv.Atoi(strings.TrimSpace(string(content))); err == nil {
+		usage.Count = count
+	}
+	return usage, nil
+}
+
+// RecordUsage bumps the invocation count of the given version the same way the generated shim
+// does. It's best effort, errors are ignored so recording never fails the user's command.
+func (env *Env) RecordUsage(version string) {
+	count := 0
+	if content, err := afero.ReadFile(Fs, env.usagePath(version)); err == nil {
+		count, _ = strconv.Atoi(strings.TrimSpace(string(content)))
+	}
+	_ = writeFileAtomic(env.usagePath(version), []byte(strconv.Itoa(count+1)), 0644)
+}
+
+// LastUsed returns the last time the shim ran the given version. Versions that have never been
+// run through the shim report their install time instead.
+func (env *Env) LastUsed(version string) (time.Time, error) {
+	stat, err := Fs.Stat(env.usagePath(version))
+	if err == nil {
+		return stat.ModTime(), nil
+	}
+	if !os.IsNotExist(err) {
+		return time.Time{}, err
+	}
+	info, err := env.Info(version)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if info != nil && !info.InstalledAt.IsZero() {
+		return info.InstalledAt, nil
+	}
+	stat, err = Fs.Stat(env.versionDir(version))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return stat.ModTime(), nil
+}
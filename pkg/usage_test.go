@@ -0,0 +1,57 @@
+package pkg_test
+
+import (
+	"time"
+
+	"github.com/lonegunmanb/genv/pkg"
+)
+
+func (d *envSuite) TestUsage() {
+	d.installedVersions("v1.0.0")
+	lastUsed := time.Now().Add(-time.Hour).Truncate(time.Second)
+	d.files(map[string][]byte{
+		"/tmp/tfenv/v1.0.0/.usage": []byte("42\n"),
+	})
+	d.Require().NoError(d.mockFs.Chtimes("/tmp/tfenv/v1.0.0/.usage", lastUsed, lastUsed))
+	sut := pkg.NewEnv("/tmp", "tfenv", "terraform", nil)
+	usage, err := sut.Usage("v1.0.0")
+	d.NoError(err)
+	d.Equal(42, usage.Count)
+	d.True(lastUsed.Equal(usage.LastUsed))
+}
+
+func (d *envSuite) TestUsage_LegacyMarker() {
+	d.installedVersions("v1.0.0")
+	d.files(map[string][]byte{
+		"/tmp/tfenv/v1.0.0/.usage": {},
+	})
+	sut := pkg.NewEnv("/tmp", "tfenv", "terraform", nil)
+	usage, err := sut.Usage("v1.0.0")
+	d.NoError(err)
+	d.Equal(0, usage.Count)
+	d.False(usage.LastUsed.IsZero())
+}
+
+func (d *envSuite) TestUsage_NeverUsedShouldFallbackToInstallTime() {
+	d.installedVersions("v1.0.0")
+	installedAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	d.files(map[string][]byte{
+		"/tmp/tfenv/v1.0.0/.genv.json": []byte(`{"version":"v1.0.0","installed_at":"2024-05-01T10:00:00Z"}`),
+	})
+	sut := pkg.NewEnv("/tmp", "tfenv", "terraform", nil)
+	usage, err := sut.Usage("v1.0.0")
+	d.NoError(err)
+	d.Equal(0, usage.Count)
+	d.True(installedAt.Equal(usage.LastUsed))
+}
+
+func (d *envSuite) TestRecordUsage() {
+	d.installedVersions("v1.0.0")
+	sut := pkg.NewEnv("/tmp", "tfenv", "terraform", nil)
+	sut.RecordUsage("v1.0.0")
+	sut.RecordUsage("v1.0.0")
+	usage, err := sut.Usage("v1.0.0")
+	d.NoError(err)
+	d.Equal(2, usage.Count)
+	d.WithinDuration(time.Now(), usage.LastUsed, time.Minute)
+}
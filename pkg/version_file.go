@@ -0,0 +1,147 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// VersionFileName returns the name of the file that pins a version for a directory, like ".terraform-version".
+func (env *Env) VersionFileName() string {
+	return fmt.Sprintf(".%s-version", env.BinaryName())
+}
+
+// SetAliases adds names, besides the primary binary name, that identify this env in .tool-versions,
+// like "golang" for an env whose binary is "go".
+func (env *Env) SetAliases(aliases ...string) {
+	env.aliases = aliases
+}
+
+// PinnedVersion returns the version pinned for dir by the nearest version file or .tool-versions
+// entry in dir or its parents, an empty string means no version is pinned. When a directory has
+// both, the version file wins.
+func (env *Env) PinnedVersion(dir string) (string, error) {
+	version, _, err := env.pinnedVersion(dir)
+	return version, err
+}
+
+// ResolveVersion returns the version in effect for dir, the pinned version or else the one
+// selected by use. It returns nil when there is neither.
+func (env *Env) ResolveVersion(dir string) (*string, error) {
+	version, err := env.PinnedVersion(dir)
+	if err != nil {
+		return nil, err
+	}
+	if version != "" {
+		return &version, nil
+	}
+	return env.CurrentVersion()
+}
+
+// SetVersionFile pins version for dir by writing the version file in it.
+func (env *Env) SetVersionFile(dir, version string) error {
+	return writeFileAtomic(filepath.Join(dir, env.VersionFileName()), []byte(version+"\n"), 0644)
+}
+
+// SetToolVersions pins version for dir in its .tool-versions. Only the line of this env is
+// touched, comments and other tools' entries are kept as they are. The line is appended with the
+// binary name when the file has no entry for this env yet.
+func (env *Env) SetToolVersions(dir, version string) error {
+	path := filepath.Join(dir, ToolVersionsName)
+	content, err := afero.ReadFile(Fs, path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return writeFileAtomic(path, setToolVersion(content, env.toolNames(), version), 0644)
+}
+
+func (env *Env) pinnedVersion(dir string) (version string, source string, err error) {
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return "", "", err
+	}
+	for {
+		path := filepath.Join(dir, env.VersionFileName())
+		version, err := readVersionFile(path)
+		if err == nil && version != "" {
+			return version, path, nil
+		}
+		if err != nil && !os.IsNotExist(err) {
+			return "", "", err
+		}
+		path = filepath.Join(dir, ToolVersionsName)
+		version, err = env.readToolVersions(path)
+		if err == nil && version != "" {
+			return version, path, nil
+		}
+		if err != nil && !os.IsNotExist(err) {
+			return "", "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", nil
+		}
+		dir = parent
+	}
+}
+
+// toolNames returns the names this env answers to in .tool-versions, the primary binary name first.
+func (env *Env) toolNames() []string {
+	return append([]string{env.BinaryName()}, env.aliases...)
+}
+
+func (env *Env) readToolVersions(path string) (string, error) {
+	content, err := afero.ReadFile(Fs, path)
+	if err != nil {
+		return "", err
+	}
+	for _, tv := range parseToolVersions(content) {
+		if slices.Contains(env.toolNames(), tv.Tool) {
+			return tv.Version, nil
+		}
+	}
+	return "", nil
+}
+
+func readVersionFile(path string) (string, error) {
+	content, err := afero.ReadFile(Fs, path)
+	if err != nil {
+		return "", err
+	}
+	lines := strings.SplitN(strings.TrimSpace(string(content)), "\n", 2)
+	return strings.TrimSpace(lines[0]), nil
+}
+
+// setToolVersion rewrites the first line whose tool is one of names to pin version, keeping
+// its trailing comment, or appends a line for names[0] when there is none.
+func setToolVersion(content []byte, names []string, version string) []byte {
+	var out bytes.Buffer
+	updated := false
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !updated {
+			entry, comment, hasComment := strings.Cut(line, "#")
+			fields := strings.Fields(entry)
+			if len(fields) > 0 && slices.Contains(names, fields[0]) {
+				line = fmt.Sprintf("%s %s", fields[0], version)
+				if hasComment {
+					line = fmt.Sprintf("%s #%s", line, comment)
+				}
+				updated = true
+			}
+		}
+		out.WriteString(line)
+		out.WriteString("\n")
+	}
+	if !updated {
+		_, _ = fmt.Fprintf(&out, "%s %s\n", names[0], version)
+	}
+	return out.Bytes()
+}
@@ -0,0 +1,140 @@
+package pkg_test
+
+import (
+	"github.com/lonegunmanb/genv/pkg"
+	"github.com/spf13/afero"
+)
+
+func (d *envSuite) TestPinnedVersion() {
+	d.files(map[string][]byte{
+		"/src/project/.terraform-version":        []byte("1.5.7\n"),
+		"/src/project/nested/.terraform-version": []byte("  1.6.0  \n# comment\n"),
+		"/src/other/.vault-version":              []byte("1.6.0"),
+	})
+	cases := []struct {
+		desc     string
+		dir      string
+		expected string
+	}{
+		{
+			desc:     "same_dir",
+			dir:      "/src/project",
+			expected: "1.5.7",
+		},
+		{
+			desc:     "parent_dir",
+			dir:      "/src/project/sub/dir",
+			expected: "1.5.7",
+		},
+		{
+			desc:     "nearest_wins",
+			dir:      "/src/project/nested",
+			expected: "1.6.0",
+		},
+		{
+			desc:     "not_pinned",
+			dir:      "/src/other",
+			expected: "",
+		},
+	}
+	sut := pkg.NewEnv("/tmp", "tfenv", "terraform", nil)
+	for _, c := range cases {
+		version, err := sut.PinnedVersion(c.dir)
+		d.NoError(err, c.desc)
+		d.Equal(c.expected, version, c.desc)
+	}
+}
+
+func (d *envSuite) TestPinnedVersion_ToolVersions() {
+	d.files(map[string][]byte{
+		"/src/.tool-versions":                []byte("# asdf\nnodejs 20.1.0\nterraform 1.5.7 1.5.6\n"),
+		"/src/project/.tool-versions":        []byte("nodejs 20.1.0\n"),
+		"/src/pinned/.terraform-version":     []byte("1.6.0"),
+		"/src/pinned/.tool-versions":         []byte("terraform 1.7.0"),
+		"/src/aliased/.tool-versions":        []byte("tf 1.4.0"),
+		"/src/pinned/nested/.tool-versions":  []byte("terraform 1.8.0"),
+		"/src/aliased/nested/.tool-versions": []byte("nodejs 20.1.0"),
+	})
+	sut := pkg.NewEnv("/tmp", "tfenv", "terraform", nil)
+	sut.SetAliases("tf")
+	for dir, expected := range map[string]string{
+		"/src":                "1.5.7",
+		"/src/project":        "1.5.7",
+		"/src/pinned":         "1.6.0",
+		"/src/pinned/nested":  "1.8.0",
+		"/src/aliased/nested": "1.4.0",
+	} {
+		version, err := sut.PinnedVersion(dir)
+		d.NoError(err, dir)
+		d.Equal(expected, version, dir)
+	}
+}
+
+func (d *envSuite) TestResolveVersion() {
+	d.files(map[string][]byte{
+		"/tmp/tfenv/.profile.json":    []byte(`{"version":"1.5.7"}`),
+		"/src/project/.tool-versions": []byte("terraform 1.6.0"),
+	})
+	sut := pkg.NewEnv("/tmp", "tfenv", "terraform", nil)
+	version, err := sut.ResolveVersion("/src/project")
+	d.NoError(err)
+	d.Equal("1.6.0", *version)
+	version, err = sut.ResolveVersion("/src")
+	d.NoError(err)
+	d.Equal("1.5.7", *version)
+}
+
+func (d *envSuite) TestSetToolVersions() {
+	cases := []struct {
+		desc     string
+		content  string
+		expected string
+	}{
+		{
+			desc:     "update_line",
+			content:  "# project tools\nnodejs 20.1.0\nterraform 1.5.7 1.5.6 # pinned for the provider\ngolang 1.22.3\n",
+			expected: "# project tools\nnodejs 20.1.0\nterraform 1.6.0 # pinned for the provider\ngolang 1.22.3\n",
+		},
+		{
+			desc:     "update_alias",
+			content:  "tf 1.5.7\n",
+			expected: "tf 1.6.0\n",
+		},
+		{
+			desc:     "append_line",
+			content:  "nodejs 20.1.0",
+			expected: "nodejs 20.1.0\nterraform 1.6.0\n",
+		},
+		{
+			desc:     "new_file",
+			content:  "",
+			expected: "terraform 1.6.0\n",
+		},
+	}
+	for _, c := range cases {
+		cc := c
+		d.Run(cc.desc, func() {
+			if cc.content != "" {
+				d.files(map[string][]byte{
+					"/src/project/.tool-versions": []byte(cc.content),
+				})
+			}
+			sut := pkg.NewEnv("/tmp", "tfenv", "terraform", nil)
+			sut.SetAliases("tf")
+			err := sut.SetToolVersions("/src/project", "1.6.0")
+			d.NoError(err)
+			content, err := afero.ReadFile(d.mockFs, "/src/project/.tool-versions")
+			d.NoError(err)
+			d.Equal(cc.expected, string(content))
+		})
+	}
+}
+
+func (d *envSuite) TestSetVersionFile() {
+	sut := pkg.NewEnv("/tmp", "tfenv", "terraform", nil)
+	err := sut.SetVersionFile("/src/project", "1.6.0")
+	d.NoError(err)
+	version, err := sut.PinnedVersion("/src/project")
+	d.NoError(err)
+	d.Equal("1.6.0", version)
+}
@@ -0,0 +1,53 @@
+package pkg
+
+import (
+	"sort"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// SortVersions sorts versions in ascending semver order, versions that aren't semver,
+// like git hashes, come last in lexical order.
+func SortVersions(versions []string) {
+	parsed := make(map[string]*semver.Version, len(versions))
+	for _, v := range versions {
+		if sv, err := semver.NewVersion(v); err == nil {
+			parsed[v] = sv
+		}
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		vi, iSemver := parsed[versions[i]]
+		vj, jSemver := parsed[versions[j]]
+		switch {
+		case iSemver && jSemver:
+			if vi.Equal(vj) {
+				return versions[i] < versions[j]
+			}
+			return vi.LessThan(vj)
+		case iSemver != jSemver:
+			return iSemver
+		default:
+			return versions[i] < versions[j]
+		}
+	})
+}
+
+// FilterVersions returns versions that satisfy the semver constraint, like ">=1.6", in their
+// original order. Versions that aren't semver never match.
+func FilterVersions(versions []string, constraint string) ([]string, error) {
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return nil, err
+	}
+	var matched []string
+	for _, v := range versions {
+		sv, err := semver.NewVersion(v)
+		if err != nil {
+			continue
+		}
+		if c.Check(sv) {
+			matched = append(matched, v)
+		}
+	}
+	return matched, nil
+}
@@ -0,0 +1,35 @@
+package pkg_test
+
+import (
+	"testing"
+
+	"github.com/lonegunmanb/genv/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortVersions(t *testing.T) {
+	versions := []string{
+		"c05e704f072ce244170d80b0d7abb09c86def826",
+		"v1.10.0",
+		"latest",
+		"1.2.0",
+		"v1.2.0-rc1",
+		"v0.9.0",
+	}
+	pkg.SortVersions(versions)
+	assert.Equal(t, []string{
+		"v0.9.0",
+		"v1.2.0-rc1",
+		"1.2.0",
+		"v1.10.0",
+		"c05e704f072ce244170d80b0d7abb09c86def826",
+		"latest",
+	}, versions)
+}
+
+func TestFilterVersions(t *testing.T) {
+	versions := []string{"v1.5.7", "1.6.0", "v1.7.0", "latest"}
+	matched, err := pkg.FilterVersions(versions, ">=1.6, <1.7")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1.6.0"}, matched)
+}
@@ -0,0 +1,95 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/afero"
+)
+
+// XdgAppName is the directory genv keeps its envs in under the XDG base directories, every env
+// gets its own sub directory, like $XDG_DATA_HOME/genv/vaultenv.
+const XdgAppName = "genv"
+
+// DataHome returns $XDG_DATA_HOME, ~/.local/share when it's not set, or %LOCALAPPDATA% on windows.
+func DataHome() (string, error) {
+	return xdgDir("XDG_DATA_HOME", func(home string) string {
+		if Os == "windows" {
+			if dir := os.Getenv("LOCALAPPDATA"); dir != "" {
+				return dir
+			}
+			return filepath.Join(home, "AppData", "Local")
+		}
+		return filepath.Join(home, ".local", "share")
+	})
+}
+
+// CacheHome returns $XDG_CACHE_HOME, ~/.cache when it's not set, or %LOCALAPPDATA% on windows.
+func CacheHome() (string, error) {
+	return xdgDir("XDG_CACHE_HOME", func(home string) string {
+		if Os == "windows" {
+			if dir := os.Getenv("LOCALAPPDATA"); dir != "" {
+				return dir
+			}
+			return filepath.Join(home, "AppData", "Local")
+		}
+		return filepath.Join(home, ".cache")
+	})
+}
+
+// ConfigHome returns $XDG_CONFIG_HOME, ~/.config when it's not set, or %APPDATA% on windows.
+func ConfigHome() (string, error) {
+	return xdgDir("XDG_CONFIG_HOME", func(home string) string {
+		if Os == "windows" {
+			if dir := os.Getenv("APPDATA"); dir != "" {
+				return dir
+			}
+			return filepath.Join(home, "AppData", "Roaming")
+		}
+		return filepath.Join(home, ".config")
+	})
+}
+
+// xdgDir returns the directory in envVar, relative paths are invalid per the XDG spec and ignored.
+func xdgDir(envVar string, fallback func(home string) string) (string, error) {
+	if dir := os.Getenv(envVar); dir != "" && filepath.IsAbs(dir) {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return fallback(home), nil
+}
+
+// MigrateEnvDir moves an env directory from an older location, like ~/vaultenv before XDG
+// directories were used, to dst. It only moves directories that hold an env, recognized by their
+// profile or lock file, and never overwrites dst. It returns true when it moved something.
+func MigrateEnvDir(src, dst string) (bool, error) {
+	if filepath.Clean(src) == filepath.Clean(dst) {
+		return false, nil
+	}
+	isEnv := false
+	for _, marker := range []string{".profile.json", ".lock"} {
+		exists, err := afero.Exists(Fs, filepath.Join(src, marker))
+		if err != nil {
+			return false, err
+		}
+		isEnv = isEnv || exists
+	}
+	if !isEnv {
+		return false, nil
+	}
+	if _, err := Fs.Stat(dst); err == nil || !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+	if err := Fs.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return false, err
+	}
+	if err := Fs.Rename(src, dst); err != nil {
+		return false, fmt.Errorf("failed to move %s to %s, move it by hand: %w", src, dst, err)
+	}
+	return true, nil
+}
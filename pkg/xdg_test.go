@@ -0,0 +1,81 @@
+package pkg_test
+
+import (
+	"path/filepath"
+
+	"github.com/lonegunmanb/genv/pkg"
+	"github.com/spf13/afero"
+)
+
+func (d *envSuite) TestXdgDirs() {
+	d.T().Setenv("HOME", "/home/user")
+	d.T().Setenv("XDG_DATA_HOME", "")
+	d.T().Setenv("XDG_CACHE_HOME", "relative/cache")
+	d.T().Setenv("XDG_CONFIG_HOME", "/xdg/config")
+	dataHome, err := pkg.DataHome()
+	d.NoError(err)
+	d.Equal(filepath.Join("/home/user", ".local", "share"), dataHome)
+	cacheHome, err := pkg.CacheHome()
+	d.NoError(err)
+	d.Equal(filepath.Join("/home/user", ".cache"), cacheHome, "relative paths must be ignored")
+	configHome, err := pkg.ConfigHome()
+	d.NoError(err)
+	d.Equal("/xdg/config", configHome)
+}
+
+func (d *envSuite) TestSetCacheDir() {
+	d.files(map[string][]byte{
+		"/xdg/cache/genv/tfenv/remote-versions.json": []byte(`{"fetched_at":"2999-01-01T00:00:00Z","versions":["1.6.0"]}`),
+	})
+	installer := listerInstaller{d.mockInstaller.(*MockInstaller), NewMockRemoteLister(d.mockCtrl)}
+	sut := pkg.NewEnv("/tmp", "tfenv", "terraform", installer)
+	sut.SetCacheDir("/xdg/cache/genv/tfenv")
+	versions, err := sut.ListRemote()
+	d.NoError(err)
+	d.Equal([]string{"1.6.0"}, versions)
+	d.NoError(sut.Reset())
+	exists, err := afero.Exists(d.mockFs, "/xdg/cache/genv/tfenv")
+	d.NoError(err)
+	d.False(exists, "Reset must clear the cache outside the env directory")
+}
+
+func (d *envSuite) TestMigrateEnvDir() {
+	d.files(map[string][]byte{
+		"/home/user/tfenv/.profile.json":    []byte(`{"version":"1.6.0"}`),
+		"/home/user/tfenv/1.6.0/terraform":  []byte("fake"),
+		"/home/user/projects/tfenv/main.tf": []byte(""),
+	})
+	migrated, err := pkg.MigrateEnvDir("/home/user/tfenv", "/xdg/data/genv/tfenv")
+	d.NoError(err)
+	d.True(migrated)
+	sut := pkg.NewEnv("/xdg/data/genv", "tfenv", "terraform", nil)
+	current, err := sut.CurrentVersion()
+	d.NoError(err)
+	d.Equal("1.6.0", *current)
+	installed, err := sut.ListInstalled()
+	d.NoError(err)
+	d.Equal([]string{"1.6.0"}, installed)
+	exists, err := afero.Exists(d.mockFs, "/home/user/tfenv")
+	d.NoError(err)
+	d.False(exists)
+
+	migrated, err = pkg.MigrateEnvDir("/home/user/tfenv", "/xdg/data/genv/tfenv")
+	d.NoError(err)
+	d.False(migrated, "nothing left to migrate")
+	migrated, err = pkg.MigrateEnvDir("/home/user/projects/tfenv", "/xdg/data/genv/other")
+	d.NoError(err)
+	d.False(migrated, "a directory that isn't an env must be left alone")
+}
+
+func (d *envSuite) TestMigrateEnvDir_DoesNotOverwrite() {
+	d.files(map[string][]byte{
+		"/home/user/tfenv/.profile.json":     []byte(`{"version":"1.5.7"}`),
+		"/xdg/data/genv/tfenv/.profile.json": []byte(`{"version":"1.6.0"}`),
+	})
+	migrated, err := pkg.MigrateEnvDir("/home/user/tfenv", "/xdg/data/genv/tfenv")
+	d.NoError(err)
+	d.False(migrated)
+	exists, err := afero.Exists(d.mockFs, "/home/user/tfenv/.profile.json")
+	d.NoError(err)
+	d.True(exists)
+}